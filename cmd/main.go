@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/kevin-luvian/amartha-recon/internal/model"
 	"github.com/kevin-luvian/amartha-recon/internal/parser"
 	"github.com/kevin-luvian/amartha-recon/internal/services"
 	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
@@ -15,17 +17,42 @@ var FILES = map[string]string{
 	"bca":     "/home/kevinluvianh/Documents/amartha-recon/bin/bca_sample.csv",
 	"dbs":     "/home/kevinluvianh/Documents/amartha-recon/bin/dbs_sample.csv",
 	"output":  "/home/kevinluvianh/Documents/amartha-recon/bin/out_sample.csv",
+
+	"open_items": "/home/kevinluvianh/Documents/amartha-recon/bin/open_items.jsonl",
+	"aging_csv":  "/home/kevinluvianh/Documents/amartha-recon/bin/aging_sample.csv",
+	"aging_json": "/home/kevinluvianh/Documents/amartha-recon/bin/aging_sample.json",
+	"full":       "/home/kevinluvianh/Documents/amartha-recon/bin/full_sample.csv",
+	"xlsx":       "/home/kevinluvianh/Documents/amartha-recon/bin/recon_sample.xlsx",
+	"manifest":   "/home/kevinluvianh/Documents/amartha-recon/bin/manifest_sample.json",
+	"html":       "/home/kevinluvianh/Documents/amartha-recon/bin/report_sample.html",
 }
 
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	reconSummary := services.NewReconSummary()
+	agingConfig := services.AgingConfig{AsOf: time.Now()}
+	agingReport, err := services.NewAgingReport(agingConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	balanceReport := services.NewBalanceCheckReport()
+	summaryAggregator, err := services.NewSummaryAggregator(0).WithBalanceCheck(balanceReport).WithAging(agingConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	metrics := pipeline.NewMetrics()
 	reconService, err := services.NewReconService(services.NewReconServiceOpts{
 		Ctx:             ctx,
 		CsvIngester:     ingester.NewCsvIngester(),
 		FilterDateRange: []string{"2025-01-01", "2026-01-01"},
+		WorkerCount:     4,
+		BufferSize:      10,
+		ShardCount:      4,
+		OpenItemsPath:   FILES["open_items"],
+		Metrics:         metrics,
 	})
 	if err != nil {
 		panic(err)
@@ -58,28 +85,107 @@ func main() {
 		panic(err)
 	}
 
-	transactionChan := pipeline.CombineChans(internalTransactionChan, dbsTransactionChan, bcaTransactionChan)
-	transactionChan = pipeline.TransformChan(transactionChan, reconService.FilterByDate)
+	transactionChan := pipeline.CombineChans(
+		reconService.Ctx,
+		[]<-chan model.Transaction{internalTransactionChan, dbsTransactionChan, bcaTransactionChan},
+		reconService.StageOptions("combine")...,
+	)
+	transactionChan = reconService.PassThroughBalanceCheck(transactionChan, balanceReport)
+	transactionChan = pipeline.TransformChan(reconService.Ctx, transactionChan, reconService.FilterByDate, reconService.StageOptions("filter_date")...)
 	reconTransactionChan, err := reconService.Reconcile(transactionChan)
 	if err != nil {
 		panic(err)
 	}
 
-	reconTransactionChan = reconService.PassThroughSummary(reconTransactionChan, reconSummary)
-	mismatchedChan := pipeline.TransformChan(reconTransactionChan, reconService.FilterMismatched)
+	reconTransactionChan = reconService.PassThroughOpenItems(reconTransactionChan)
+	reconTransactionChan = reconService.PassThroughAging(reconTransactionChan, agingReport)
+
+	// the workbook, which also fills the summary, and the csv writers consume the same stream independently
+	reconOutputs := pipeline.Tee(reconService.Ctx, reconTransactionChan, 4, reconService.StageOptions("tee")...)
+	pipeline.Go(reconService.Ctx, func() error {
+		return reconService.WriteXlsx(FILES["xlsx"], reconOutputs[0], summaryAggregator)
+	})
+	pipeline.Go(reconService.Ctx, func() error {
+		// counts into its own aggregator, the workbook already fills summaryAggregator
+		htmlAggregator, err := services.NewSummaryAggregator(0).WithBalanceCheck(balanceReport).WithAging(agingConfig)
+		if err != nil {
+			return err
+		}
+		return reconService.WriteHtml(FILES["html"], reconOutputs[3], htmlAggregator)
+	})
+	pipeline.Go(reconService.Ctx, func() error {
+		return reconService.WriteFullReportCsv(FILES["full"], reconOutputs[2])
+	})
+
+	mismatchedChan := pipeline.TransformChan(reconService.Ctx, reconOutputs[1], reconService.FilterMismatched, reconService.StageOptions("filter_mismatched")...)
 	err = reconService.WriteToCsv(FILES["output"], mismatchedChan)
 	if err != nil {
 		panic(err)
 	}
 
+	if err := reconService.Wait(); err != nil {
+		panic(err)
+	}
+
+	reconSummary := summaryAggregator.Snapshot()
+
+	if err := agingReport.WriteCsv(FILES["aging_csv"]); err != nil {
+		panic(err)
+	}
+	if err := agingReport.WriteJson(FILES["aging_json"]); err != nil {
+		panic(err)
+	}
+
+	manifest, err := reconService.Manifest(
+		reconSummary,
+		FILES["output"], FILES["full"], FILES["xlsx"], FILES["html"], FILES["aging_csv"], FILES["aging_json"], FILES["open_items"],
+	)
+	if err != nil {
+		panic(err)
+	}
+	if err := manifest.WriteJson(FILES["manifest"]); err != nil {
+		panic(err)
+	}
+
 	fmt.Println("====== Reconciliation Summary ======")
-	fmt.Printf("Total Processed Transactions: %d\n", reconSummary.TotalMatched+reconSummary.TotalMismatched)
-	fmt.Printf("Total Matched Transactions: %d\n", reconSummary.TotalMatched)
-	fmt.Printf("Total Mismatched Transactions: %d\n", reconSummary.TotalMismatched)
-	fmt.Printf("Total Mismatches by Source:\n")
-	for source, count := range reconSummary.TotalMismatchBySource {
-		fmt.Printf("  - %s: %d mismatches\n", source, count)
+	fmt.Printf("Total Processed Transactions: %d\n", reconSummary.TotalProcessed())
+	fmt.Printf("Total Matched Pairs: %d\n", reconSummary.TotalMatched)
+	fmt.Printf("Total Partial Legs: %d\n", reconSummary.TotalPartialLegs)
+	for rule, count := range reconSummary.MatchedByRule {
+		fmt.Printf("  - %s: %d pairs\n", rule, count)
+	}
+	fmt.Printf("Total Mismatched Transactions: %d (%.2f)\n", reconSummary.TotalMismatched, reconSummary.TotalUnmatchedAmount)
+	fmt.Printf("Total Errors: %d\n", reconSummary.TotalErrors)
+	for code, count := range reconSummary.ErrorsByCode {
+		fmt.Printf("  - %s: %d errors\n", code, count)
+	}
+	fmt.Printf("Total Self-Cancelled Transactions: %d\n", reconSummary.TotalSelfCancelled)
+	fmt.Printf("By Source:\n")
+	for source, totals := range reconSummary.BySource {
+		fmt.Printf("  - %s: %d matched (%.2f), %d unmatched (%.2f), %d errors\n", source, totals.MatchedCount, totals.MatchedAmount, totals.UnmatchedCount, totals.UnmatchedAmount, totals.ErrorCount)
+	}
+	fmt.Printf("By Type:\n")
+	for transactionType, totals := range reconSummary.ByType {
+		fmt.Printf("  - %s: %d matched (%.2f), %d unmatched (%.2f), %d errors\n", transactionType, totals.MatchedCount, totals.MatchedAmount, totals.UnmatchedCount, totals.UnmatchedAmount, totals.ErrorCount)
 	}
 	fmt.Printf("Total Discrepancy Amount: %.2f\n", reconSummary.TotalDiscrepancy)
+	fmt.Printf("Unmatched Aging:\n")
+	for _, bucket := range services.DefaultAgingBuckets() {
+		fmt.Printf("  - %s days: %d breaks, %.2f\n", bucket.Label, reconSummary.AgingCountByBucket[bucket.Label], reconSummary.AgingAmountByBucket[bucket.Label])
+	}
 	fmt.Println("====================================")
+
+	fmt.Println("Pipeline stages:")
+	for _, stats := range metrics.Stats() {
+		fmt.Printf("  - %s\n", stats)
+	}
+
+	if len(reconSummary.UntrustedSources) > 0 {
+		fmt.Println("WARNING: statement balance verification failed, results for these sources cannot be trusted")
+		for source, statements := range reconSummary.UntrustedSources {
+			for _, statement := range statements {
+				fmt.Printf("  - %s %s\n", source, statement)
+			}
+		}
+	}
 }
@@ -3,13 +3,46 @@ package model
 import "fmt"
 
 type Transaction struct {
-	Source     string
-	Id         string
-	Type       string
-	Amount     float64 // 10.51 max 2 decimal places
-	Date       string  // YYYY-MM-DD
-	DateEpoch  int64   // Unix epoch time
-	ParseError error
+	Source         string
+	Id             string
+	Type           string
+	Amount         float64 // 10.51 max 2 decimal places
+	Date           string  // YYYY-MM-DD
+	DateEpoch      int64   // Unix epoch time
+	Reference      string  // bank / customer reference, optional
+	Narrative      string  // free text description, optional
+	VirtualAccount string  // virtual account number identifying the borrower or loan, optional
+	ReversalOf     string  // id or reference of the entry of the same source this one reverses, optional
+	SourceFile     string  // file the transaction was read from
+	SourceRow      int     // 1-based record index within SourceFile
+	Balance        StatementBalance
+	ParseError     error
+
+	CarriedForward  bool // open item left unmatched by a previous run
+	CarriedResidual bool // open item carrying the residual of a partial match, matched by amount whatever its date
+}
+
+// Balances of the bank statement a transaction was read from, only filled when the source reports them
+type StatementBalance struct {
+	StatementId    string  // defaults to SourceFile when empty
+	OpeningBalance float64 // signed, debit balances are negative
+	HasOpening     bool
+	ClosingBalance float64 // signed, debit balances are negative
+	HasClosing     bool
+	RunningBalance float64 // balance after this transaction
+	HasRunning     bool
+}
+
+func (b StatementBalance) IsEmpty() bool {
+	return !b.HasOpening && !b.HasClosing && !b.HasRunning
+}
+
+// SignedAmount is positive for CREDIT and negative for DEBIT
+func (t *Transaction) SignedAmount() float64 {
+	if t.Type == "DEBIT" {
+		return -t.Amount
+	}
+	return t.Amount
 }
 
 func (t Transaction) Hash() (string, []string) {
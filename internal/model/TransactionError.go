@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrorCode classifies why a record could not be reconciled, it is stable
+// across releases so downstream tooling can route on it
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidDate    ErrorCode = "INVALID_DATE"
+	ErrorCodeInvalidAmount  ErrorCode = "INVALID_AMOUNT"
+	ErrorCodeNegativeAmount ErrorCode = "NEGATIVE_AMOUNT"
+	ErrorCodeMissingField   ErrorCode = "MISSING_FIELD"
+	ErrorCodeDuplicate      ErrorCode = "DUPLICATE"     // same date, type and id as a transaction still unmatched
+	ErrorCodeOutOfRange     ErrorCode = "OUT_OF_RANGE"  // number or date component beyond its range
+	ErrorCodeInvalidField   ErrorCode = "INVALID_FIELD" // any other unreadable field, like an unknown debit/credit mark
+	ErrorCodeUnknown        ErrorCode = "UNKNOWN"       // error without a code
+)
+
+// TransactionError is a ParseError with its code and the field it is about
+type TransactionError struct {
+	Code  ErrorCode
+	Field string // record field, empty when the error is about the whole record
+	Value string // raw value of Field as read
+	Err   error
+}
+
+func (e *TransactionError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// TransactionErrors collects every field error of one record, in field order,
+// so a row failing on several fields reports all of them
+type TransactionErrors []*TransactionError
+
+func (e TransactionErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e TransactionErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns the collected errors as a ParseError, nil when there are none
+func (e TransactionErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ErrorCode returns the code of ParseError, the first one when it holds
+// several, empty when there is none
+func (t Transaction) ErrorCode() ErrorCode {
+	if t.ParseError == nil {
+		return ""
+	}
+
+	var transactionError *TransactionError
+	if errors.As(t.ParseError, &transactionError) {
+		return transactionError.Code
+	}
+	return ErrorCodeUnknown
+}
+
+// FieldErrors returns every typed error of ParseError, empty when it holds none
+func (t Transaction) FieldErrors() []*TransactionError {
+	var transactionErrors TransactionErrors
+	if errors.As(t.ParseError, &transactionErrors) {
+		return transactionErrors
+	}
+
+	var transactionError *TransactionError
+	if errors.As(t.ParseError, &transactionError) {
+		return []*TransactionError{transactionError}
+	}
+	return nil
+}
+
+// ErrorCodes returns the distinct codes of ParseError in order, empty when there is none
+func (t Transaction) ErrorCodes() []ErrorCode {
+	if t.ParseError == nil {
+		return nil
+	}
+
+	fieldErrors := t.FieldErrors()
+	if len(fieldErrors) == 0 {
+		return []ErrorCode{ErrorCodeUnknown}
+	}
+
+	codes := []ErrorCode{}
+	for _, err := range fieldErrors {
+		if !slices.Contains(codes, err.Code) {
+			codes = append(codes, err.Code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestTransaction_ErrorCode(t *testing.T) {
+	cause := errors.New("invalid syntax")
+	testCases := []struct {
+		label   string
+		err     error
+		code    ErrorCode
+		message string
+	}{
+		{"no error", nil, "", ""},
+		{"untyped", cause, ErrorCodeUnknown, "invalid syntax"},
+		{"typed", &TransactionError{Code: ErrorCodeInvalidAmount, Field: "amount", Err: cause}, ErrorCodeInvalidAmount, "amount: invalid syntax"},
+		{"whole record", &TransactionError{Code: ErrorCodeDuplicate, Err: cause}, ErrorCodeDuplicate, "invalid syntax"},
+		{"wrapped", fmt.Errorf("row 3: %w", &TransactionError{Code: ErrorCodeInvalidDate, Field: "date", Err: cause}), ErrorCodeInvalidDate, "row 3: date: invalid syntax"},
+	}
+
+	for _, testCase := range testCases {
+		txn := Transaction{ParseError: testCase.err}
+		if code := txn.ErrorCode(); code != testCase.code {
+			t.Errorf("[%s] Expected %q, got %q", testCase.label, testCase.code, code)
+		}
+		if testCase.err != nil && testCase.err.Error() != testCase.message {
+			t.Errorf("[%s] Expected %q, got %q", testCase.label, testCase.message, testCase.err.Error())
+		}
+		if testCase.err != nil && !errors.Is(testCase.err, cause) {
+			t.Errorf("[%s] Expected the cause to unwrap", testCase.label)
+		}
+	}
+}
+
+func TestTransactionErrors(t *testing.T) {
+	cause := errors.New("invalid syntax")
+	errs := TransactionErrors{
+		{Code: ErrorCodeInvalidDate, Field: "date", Value: "2025.01.01", Err: cause},
+		{Code: ErrorCodeInvalidAmount, Field: "amount", Value: "ten", Err: cause},
+		{Code: ErrorCodeInvalidAmount, Field: "balance", Value: "x", Err: cause},
+	}
+
+	if TransactionErrors(nil).Err() != nil {
+		t.Errorf("Expected no error when empty")
+	}
+
+	txn := Transaction{ParseError: errs.Err()}
+	if message := txn.ParseError.Error(); message != "date: invalid syntax; amount: invalid syntax; balance: invalid syntax" {
+		t.Errorf("Unexpected message %q", message)
+	}
+	if code := txn.ErrorCode(); code != ErrorCodeInvalidDate {
+		t.Errorf("Expected the first code, got %q", code)
+	}
+	if codes := txn.ErrorCodes(); !slices.Equal(codes, []ErrorCode{ErrorCodeInvalidDate, ErrorCodeInvalidAmount}) {
+		t.Errorf("Expected distinct codes in order, got %v", codes)
+	}
+	if fieldErrors := txn.FieldErrors(); len(fieldErrors) != 3 || fieldErrors[1].Value != "ten" {
+		t.Errorf("Expected every field error, got %v", fieldErrors)
+	}
+	if !errors.Is(txn.ParseError, cause) {
+		t.Errorf("Expected the cause to unwrap")
+	}
+
+	untyped := Transaction{ParseError: cause}
+	if codes := untyped.ErrorCodes(); !slices.Equal(codes, []ErrorCode{ErrorCodeUnknown}) || untyped.FieldErrors() != nil {
+		t.Errorf("Expected an unknown code without field errors, got %v", codes)
+	}
+}
@@ -11,10 +11,13 @@ import (
 )
 
 type AmarthaCsv struct {
-	Id     string `mapstructure:"id"`
-	Type   string `mapstructure:"type"`
-	Amount string `mapstructure:"amount"`
-	Date   string `mapstructure:"date"` // YYYY-MM-DD HH:MM:SSZ
+	Id             string `mapstructure:"id"`
+	Type           string `mapstructure:"type"`
+	Amount         string `mapstructure:"amount"`
+	Date           string `mapstructure:"date"`            // YYYY-MM-DD HH:MM:SSZ
+	VaNumber       string `mapstructure:"va_number"`       // optional
+	VirtualAccount string `mapstructure:"virtual_account"` // optional, va_number under its other name
+	ReversalOf     string `mapstructure:"reversal_of"`     // optional, id of the entry cancelled by this one
 }
 
 type AmarthaParser struct {
@@ -26,31 +29,33 @@ func NewAmarthaParser() *AmarthaParser {
 
 func (a *AmarthaParser) Parse(record map[string]string) model.Transaction {
 	var amarthaCsv AmarthaCsv
-	var parseErr error
+	var parseErrs model.TransactionErrors
 
 	if err := mapstructure.Decode(record, &amarthaCsv); err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeInvalidField, Err: err})
 	}
 
 	t, err := time.Parse(time.DateTime, amarthaCsv.Date)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidDate, "date", amarthaCsv.Date, err))
 	}
 
 	tMidnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 
 	amountf64, err := strconv.ParseFloat(amarthaCsv.Amount, 64)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "amount", amarthaCsv.Amount, err))
 	}
 
 	return model.Transaction{
-		Source:     "amartha",
-		Id:         amarthaCsv.Id,
-		Type:       amarthaCsv.Type,
-		Amount:     amountf64,
-		Date:       tMidnight.Format("2006-01-02"),
-		DateEpoch:  tMidnight.UnixMilli(),
-		ParseError: parseErr,
+		Source:         "amartha",
+		Id:             amarthaCsv.Id,
+		Type:           amarthaCsv.Type,
+		Amount:         amountf64,
+		Date:           tMidnight.Format("2006-01-02"),
+		DateEpoch:      tMidnight.UnixMilli(),
+		VirtualAccount: virtualAccount(amarthaCsv.VaNumber, amarthaCsv.VirtualAccount),
+		ReversalOf:     amarthaCsv.ReversalOf,
+		ParseError:     parseErrs.Err(),
 	}
 }
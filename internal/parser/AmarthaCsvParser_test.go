@@ -41,6 +41,15 @@ func TestAmarthaCsvParser_Parse(t *testing.T) {
 			}
 			return nil
 		},
+	}, {
+		Label: "match reversal reference",
+		Args:  map[string]string{"reversal_of": "loan_1"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ReversalOf != "loan_1" {
+				return fmt.Errorf("Expected loan_1, got %q", txn.ReversalOf)
+			}
+			return nil
+		},
 	}, {
 		Label: "error parsing amount",
 		Args:  map[string]string{"amount": "a"},
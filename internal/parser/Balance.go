@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseBalance reads an optional balance field, debit balances are returned negative
+func parseBalance(value string, isDebit bool) (float64, bool, error) {
+	if value == "" {
+		return 0, false, nil
+	}
+
+	balance, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if isDebit {
+		balance = -balance
+	}
+
+	return balance, true, nil
+}
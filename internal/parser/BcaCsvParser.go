@@ -12,9 +12,13 @@ import (
 )
 
 type BcaCsv struct {
-	Id     string `mapstructure:"ext_id"`
-	Amount string `mapstructure:"amount"`
-	Date   string `mapstructure:"date"` // YYYY-MM-DD
+	Id             string `mapstructure:"ext_id"`
+	Amount         string `mapstructure:"amount"`
+	Date           string `mapstructure:"date"`            // YYYY-MM-DD
+	Balance        string `mapstructure:"balance"`         // running balance, optional
+	VaNumber       string `mapstructure:"va_number"`       // optional
+	VirtualAccount string `mapstructure:"virtual_account"` // optional, va_number under its other name
+	ReversalOf     string `mapstructure:"reversal_of"`     // optional, ext_id of the entry reversed by this one
 }
 
 type BcaParser struct {
@@ -26,20 +30,26 @@ func NewBcaParser() *BcaParser {
 
 func (a *BcaParser) Parse(record map[string]string) model.Transaction {
 	var bcaCsv BcaCsv
-	var parseErr error
+	var parseErrs model.TransactionErrors
 
 	if err := mapstructure.Decode(record, &bcaCsv); err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeInvalidField, Err: err})
 	}
 
 	t, err := time.Parse(time.DateOnly, bcaCsv.Date)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidDate, "date", bcaCsv.Date, err))
 	}
 
 	amountf64, err := strconv.ParseFloat(bcaCsv.Amount, 64)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "amount", bcaCsv.Amount, err))
+	}
+
+	var balance model.StatementBalance
+	balance.RunningBalance, balance.HasRunning, err = parseBalance(bcaCsv.Balance, false)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "balance", bcaCsv.Balance, err))
 	}
 
 	txnType := "CREDIT"
@@ -48,12 +58,15 @@ func (a *BcaParser) Parse(record map[string]string) model.Transaction {
 	}
 
 	return model.Transaction{
-		Source:     "bca",
-		Id:         bcaCsv.Id,
-		Type:       txnType,
-		Amount:     math.Abs(amountf64),
-		Date:       t.Format("2006-01-02"),
-		DateEpoch:  t.UnixMilli(),
-		ParseError: parseErr,
+		Source:         "bca",
+		Id:             bcaCsv.Id,
+		Type:           txnType,
+		Amount:         math.Abs(amountf64),
+		Date:           t.Format("2006-01-02"),
+		DateEpoch:      t.UnixMilli(),
+		Balance:        balance,
+		VirtualAccount: virtualAccount(bcaCsv.VaNumber, bcaCsv.VirtualAccount),
+		ReversalOf:     bcaCsv.ReversalOf,
+		ParseError:     parseErrs.Err(),
 	}
 }
@@ -53,6 +53,17 @@ func TestBcaCsvParser_Parse(t *testing.T) {
 			}
 			return nil
 		},
+	}, {
+		Label: "match running balance",
+		Args: map[string]string{
+			"balance": "-7.05",
+		},
+		CheckExpected: func(txn model.Transaction) error {
+			if !txn.Balance.HasRunning || txn.Balance.RunningBalance != -7.05 {
+				return fmt.Errorf("Expected -7.05, got %.2f", txn.Balance.RunningBalance)
+			}
+			return nil
+		},
 	}, {
 		Label: "error parsing date",
 		Args: map[string]string{
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+type CamtRecord struct {
+	Amount                   string `mapstructure:"amount"`
+	CreditDebit              string `mapstructure:"credit_debit"` // CRDT, DBIT
+	Reversal                 string `mapstructure:"reversal"`     // true, false, CreditDebit is already the direction of the reversal
+	BookingDate              string `mapstructure:"booking_date"` // YYYY-MM-DD or YYYY-MM-DDThh:mm:ss
+	ValueDate                string `mapstructure:"value_date"`
+	AccountServicerReference string `mapstructure:"account_servicer_reference"`
+	EndToEndId               string `mapstructure:"end_to_end_id"`
+	EntryReference           string `mapstructure:"entry_reference"`
+	Narrative                string `mapstructure:"narrative"`
+	StatementId              string `mapstructure:"statement_id"`
+	OpeningBalance           string `mapstructure:"opening_balance"`
+	OpeningCreditDebit       string `mapstructure:"opening_credit_debit"`
+	ClosingBalance           string `mapstructure:"closing_balance"`
+	ClosingCreditDebit       string `mapstructure:"closing_credit_debit"`
+}
+
+type CamtParser struct {
+	source string
+}
+
+// camt.053 / camt.054 is shared by many banks, source names the bank the statement came from
+func NewCamtParser(source string) *CamtParser {
+	return &CamtParser{source: source}
+}
+
+func (a *CamtParser) Parse(record map[string]string) model.Transaction {
+	var camt CamtRecord
+	var parseErrs model.TransactionErrors
+
+	if err := mapstructure.Decode(record, &camt); err != nil {
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeInvalidField, Err: err})
+	}
+
+	dateField, dateStr := "booking_date", camt.BookingDate
+	if dateStr == "" {
+		dateField, dateStr = "value_date", camt.ValueDate
+	}
+
+	t, err := parseCamtDate(dateStr)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidDate, dateField, dateStr, err))
+	}
+
+	amountf64, err := strconv.ParseFloat(camt.Amount, 64)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "amount", camt.Amount, err))
+	}
+
+	var txnType string
+	switch camt.CreditDebit {
+	case "CRDT":
+		txnType = "CREDIT"
+	case "DBIT":
+		txnType = "DEBIT"
+	default:
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidField, "credit_debit", camt.CreditDebit, fmt.Errorf("invalid credit/debit indicator %q", camt.CreditDebit)))
+	}
+
+	var balance model.StatementBalance
+	balance.StatementId = camt.StatementId
+
+	balance.OpeningBalance, balance.HasOpening, err = parseBalance(camt.OpeningBalance, camt.OpeningCreditDebit == "DBIT")
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "opening_balance", camt.OpeningBalance, err))
+	}
+
+	balance.ClosingBalance, balance.HasClosing, err = parseBalance(camt.ClosingBalance, camt.ClosingCreditDebit == "DBIT")
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "closing_balance", camt.ClosingBalance, err))
+	}
+
+	// NOTPROVIDED is used when the originator gave no end to end id
+	id := camt.EndToEndId
+	if id == "" || id == "NOTPROVIDED" {
+		id = camt.AccountServicerReference
+	}
+	if id == "" {
+		id = camt.EntryReference
+	}
+
+	// a reversal carries the end to end id of the entry it reverses
+	var reversalOf string
+	if camt.Reversal == "true" && camt.EndToEndId != "NOTPROVIDED" {
+		reversalOf = camt.EndToEndId
+	}
+
+	return model.Transaction{
+		Source:     a.source,
+		Id:         id,
+		Type:       txnType,
+		Amount:     amountf64,
+		Date:       t.Format("2006-01-02"),
+		DateEpoch:  t.UnixMilli(),
+		Reference:  camt.AccountServicerReference,
+		Narrative:  camt.Narrative,
+		ReversalOf: reversalOf,
+		Balance:    balance,
+		ParseError: parseErrs.Err(),
+	}
+}
+
+// parseCamtDate accepts ISODate and ISODateTime, date times are truncated to midnight
+func parseCamtDate(value string) (time.Time, error) {
+	if len(value) <= len(time.DateOnly) {
+		return time.Parse(time.DateOnly, value)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// ISODateTime without offset
+		t, err = time.Parse("2006-01-02T15:04:05", value)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+type TestCamtParser_ParseArgs struct {
+	Label         string
+	Args          map[string]string
+	CheckExpected func(txn model.Transaction) error
+}
+
+func TestCamtParser_Parse(t *testing.T) {
+	testCases := []TestCamtParser_ParseArgs{{
+		Label: "match entry",
+		Args: map[string]string{
+			"amount":                     "4.05",
+			"credit_debit":               "DBIT",
+			"booking_date":               "2025-01-01",
+			"account_servicer_reference": "BANKREF",
+			"end_to_end_id":              "123",
+			"narrative":                  "PAYOUT",
+		},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError != nil {
+				return fmt.Errorf("Expected nil, got %v", txn.ParseError)
+			}
+			if txn.Source != "bca" {
+				return fmt.Errorf("Expected bca, got %s", txn.Source)
+			}
+			if txn.Id != "123" {
+				return fmt.Errorf("Expected 123, got %s", txn.Id)
+			}
+			if txn.Type != "DEBIT" {
+				return fmt.Errorf("Expected DEBIT, got %s", txn.Type)
+			}
+			if txn.Amount != 4.05 {
+				return fmt.Errorf("Expected 4.05, got %.2f", txn.Amount)
+			}
+			if txn.Reference != "BANKREF" {
+				return fmt.Errorf("Expected BANKREF, got %s", txn.Reference)
+			}
+			if txn.Narrative != "PAYOUT" {
+				return fmt.Errorf("Expected PAYOUT, got %s", txn.Narrative)
+			}
+			return nil
+		},
+	}, {
+		Label: "match statement balances",
+		Args: map[string]string{
+			"statement_id":         "STMT1",
+			"opening_balance":      "100.50",
+			"opening_credit_debit": "DBIT",
+			"closing_balance":      "10",
+			"closing_credit_debit": "CRDT",
+		},
+		CheckExpected: func(txn model.Transaction) error {
+			balance := txn.Balance
+			if balance.StatementId != "STMT1" {
+				return fmt.Errorf("Expected STMT1, got %s", balance.StatementId)
+			}
+			if !balance.HasOpening || balance.OpeningBalance != -100.5 {
+				return fmt.Errorf("Expected -100.50, got %.2f", balance.OpeningBalance)
+			}
+			if !balance.HasClosing || balance.ClosingBalance != 10 {
+				return fmt.Errorf("Expected 10.00, got %.2f", balance.ClosingBalance)
+			}
+			return nil
+		},
+	}, {
+		Label: "match id from account servicer reference",
+		Args:  map[string]string{"end_to_end_id": "NOTPROVIDED", "account_servicer_reference": "BANKREF"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.Id != "BANKREF" {
+				return fmt.Errorf("Expected BANKREF, got %s", txn.Id)
+			}
+			return nil
+		},
+	}, {
+		Label: "match date time and value date",
+		Args:  map[string]string{"value_date": "2025-01-01T23:00:00+07:00"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.Date != "2025-01-01" {
+				return fmt.Errorf("Expected 2025-01-01, got %s", txn.Date)
+			}
+			return nil
+		},
+	}, {
+		// the indicator of a reversal entry is already its booking direction, a reversed debit is credited
+		Label: "match reversal type",
+		Args:  map[string]string{"credit_debit": "CRDT", "reversal": "true"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.Type != "CREDIT" {
+				return fmt.Errorf("Expected CREDIT, got %s", txn.Type)
+			}
+			return nil
+		},
+	}, {
+		Label: "match reversal reference",
+		Args:  map[string]string{"credit_debit": "CRDT", "reversal": "true", "end_to_end_id": "E2E1", "account_servicer_reference": "ASR2"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ReversalOf != "E2E1" {
+				return fmt.Errorf("Expected E2E1, got %q", txn.ReversalOf)
+			}
+			return nil
+		},
+	}, {
+		Label: "error parsing indicator",
+		Args:  map[string]string{"amount": "1", "booking_date": "2025-01-01", "credit_debit": "X"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError == nil {
+				return fmt.Errorf("Expected ParseError, got nil")
+			}
+			return nil
+		},
+	}, {
+		Label: "error parsing date",
+		Args:  map[string]string{"amount": "1", "booking_date": "2025.01.01", "credit_debit": "CRDT"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError == nil {
+				return fmt.Errorf("Expected ParseError, got nil")
+			}
+			if txn.Date != "0001-01-01" {
+				return fmt.Errorf("Expected 0001-01-01, got %s", txn.Date)
+			}
+			return nil
+		},
+	}}
+
+	newParser := NewCamtParser("bca")
+	for _, testCase := range testCases {
+		record := testCase.Args
+		txn := newParser.Parse(record)
+		err := testCase.CheckExpected(txn)
+		if err != nil {
+			t.Errorf("[%s] %v", testCase.Label, err)
+		}
+	}
+}
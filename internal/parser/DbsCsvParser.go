@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/kevin-luvian/amartha-recon/internal/model"
@@ -12,10 +11,14 @@ import (
 )
 
 type DbsCsv struct {
-	Id     string `mapstructure:"ext_id"`
-	Type   string `mapstructure:"type"`
-	Amount string `mapstructure:"amount"`
-	Date   string `mapstructure:"date"` // YYYY-MM-DD
+	Id             string `mapstructure:"ext_id"`
+	Type           string `mapstructure:"type"`
+	Amount         string `mapstructure:"amount"`
+	Date           string `mapstructure:"date"`            // YYYY-MM-DD
+	Balance        string `mapstructure:"balance"`         // running balance, optional
+	VaNumber       string `mapstructure:"va_number"`       // optional
+	VirtualAccount string `mapstructure:"virtual_account"` // optional, va_number under its other name
+	ReversalOf     string `mapstructure:"reversal_of"`     // optional, ext_id of the entry reversed by this one
 }
 
 type DbsParser struct {
@@ -27,33 +30,42 @@ func NewDbsParser() *DbsParser {
 
 func (a *DbsParser) Parse(record map[string]string) model.Transaction {
 	var dbsCsv DbsCsv
-	var parseErr error
+	var parseErrs model.TransactionErrors
 
 	if err := mapstructure.Decode(record, &dbsCsv); err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeInvalidField, Err: err})
 	}
 
 	t, err := time.Parse(time.DateOnly, dbsCsv.Date)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidDate, "date", dbsCsv.Date, err))
 	}
 
 	amountf64, err := strconv.ParseFloat(dbsCsv.Amount, 64)
 	if err != nil {
-		parseErr = err
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "amount", dbsCsv.Amount, err))
+	}
+
+	var balance model.StatementBalance
+	balance.RunningBalance, balance.HasRunning, err = parseBalance(dbsCsv.Balance, false)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "balance", dbsCsv.Balance, err))
 	}
 
 	if amountf64 < 0 {
-		parseErr = fmt.Errorf("negative amount provided")
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeNegativeAmount, Field: "amount", Value: dbsCsv.Amount, Err: errNegativeAmount})
 	}
 
 	return model.Transaction{
-		Source:     "dbs",
-		Id:         dbsCsv.Id,
-		Type:       dbsCsv.Type,
-		Amount:     amountf64,
-		Date:       t.Format("2006-01-02"),
-		DateEpoch:  t.UnixMilli(),
-		ParseError: parseErr,
+		Source:         "dbs",
+		Id:             dbsCsv.Id,
+		Type:           dbsCsv.Type,
+		Amount:         amountf64,
+		Date:           t.Format("2006-01-02"),
+		DateEpoch:      t.UnixMilli(),
+		Balance:        balance,
+		VirtualAccount: virtualAccount(dbsCsv.VaNumber, dbsCsv.VirtualAccount),
+		ReversalOf:     dbsCsv.ReversalOf,
+		ParseError:     parseErrs.Err(),
 	}
 }
@@ -53,6 +53,31 @@ func TestDbsCsvParser_Parse(t *testing.T) {
 			}
 			return nil
 		},
+	}, {
+		Label: "error parsing running balance",
+		Args:  map[string]string{"ext_id": "123", "type": "CREDIT", "amount": "1", "date": "2025-01-01", "balance": "a"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError == nil {
+				return fmt.Errorf("Expected ParseError, got nil")
+			}
+			if txn.Balance.HasRunning {
+				return fmt.Errorf("Expected no running balance, got %.2f", txn.Balance.RunningBalance)
+			}
+			return nil
+		},
+	}, {
+		Label: "error negative amount",
+		Args:  map[string]string{"ext_id": "123", "type": "CREDIT", "amount": "-5.00", "date": "2025-01-01"},
+		CheckExpected: func(txn model.Transaction) error {
+			fieldErrors := txn.FieldErrors()
+			if len(fieldErrors) != 1 || fieldErrors[0].Code != model.ErrorCodeNegativeAmount {
+				return fmt.Errorf("Expected a NEGATIVE_AMOUNT error, got %v", txn.ParseError)
+			}
+			if fieldErrors[0].Value != "-5.00" {
+				return fmt.Errorf("Expected the raw amount -5.00, got %q", fieldErrors[0].Value)
+			}
+			return nil
+		},
 	}, {
 		Label: "error parsing date",
 		Args:  map[string]string{"date": "2025.01.01"},
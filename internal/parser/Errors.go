@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+var (
+	errMissingValue   = errors.New("missing value")
+	errNegativeAmount = errors.New("negative amount provided")
+)
+
+// fieldError types the failure to parse value of field as code, an empty
+// value is reported as missing and a value beyond its range as out of range
+func fieldError(code model.ErrorCode, field string, value string, err error) *model.TransactionError {
+	var timeErr *time.ParseError
+
+	switch {
+	case strings.TrimSpace(value) == "":
+		code, err = model.ErrorCodeMissingField, errMissingValue
+	case errors.Is(err, strconv.ErrRange):
+		code = model.ErrorCodeOutOfRange
+	case errors.As(err, &timeErr) && strings.HasSuffix(timeErr.Message, "out of range"):
+		code = model.ErrorCodeOutOfRange
+	}
+
+	return &model.TransactionError{Code: code, Field: field, Value: value, Err: err}
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func TestParser_ErrorCodes(t *testing.T) {
+	valid := map[string]string{"ext_id": "1", "type": "DEBIT", "amount": "10", "date": "2025-01-01"}
+	with := func(field string, value string) map[string]string {
+		record := map[string]string{}
+		for key, existing := range valid {
+			record[key] = existing
+		}
+		record[field] = value
+		return record
+	}
+
+	testCases := []struct {
+		label  string
+		record map[string]string
+		code   model.ErrorCode
+		field  string
+	}{
+		{"valid", valid, "", ""},
+		{"invalid date", with("date", "2025.01.01"), model.ErrorCodeInvalidDate, "date"},
+		{"date out of range", with("date", "2025-13-01"), model.ErrorCodeOutOfRange, "date"},
+		{"missing date", with("date", ""), model.ErrorCodeMissingField, "date"},
+		{"invalid amount", with("amount", "ten"), model.ErrorCodeInvalidAmount, "amount"},
+		{"amount out of range", with("amount", "1e400"), model.ErrorCodeOutOfRange, "amount"},
+		{"missing amount", with("amount", " "), model.ErrorCodeMissingField, "amount"},
+		{"negative amount", with("amount", "-10"), model.ErrorCodeNegativeAmount, "amount"},
+		{"invalid balance", with("balance", "x"), model.ErrorCodeInvalidAmount, "balance"},
+	}
+
+	newParser := NewDbsParser()
+	for _, testCase := range testCases {
+		txn := newParser.Parse(testCase.record)
+
+		if code := txn.ErrorCode(); code != testCase.code {
+			t.Errorf("[%s] Expected code %q, got %q (%v)", testCase.label, testCase.code, code, txn.ParseError)
+			continue
+		}
+
+		var transactionError *model.TransactionError
+		if testCase.code != "" && (!errors.As(txn.ParseError, &transactionError) || transactionError.Field != testCase.field) {
+			t.Errorf("[%s] Expected field %s, got %v", testCase.label, testCase.field, txn.ParseError)
+		}
+	}
+}
+
+func TestParser_InvalidMarkErrorCode(t *testing.T) {
+	camt := NewCamtParser("bank").Parse(map[string]string{"amount": "1", "credit_debit": "X", "booking_date": "2025-01-01"})
+	if camt.ErrorCode() != model.ErrorCodeInvalidField || camt.ParseError.Error() != `credit_debit: invalid credit/debit indicator "X"` {
+		t.Errorf("Unexpected camt error %s %v", camt.ErrorCode(), camt.ParseError)
+	}
+
+	mt940 := NewMt940Parser("bank").Parse(map[string]string{"amount": "1,00", "value_date": "250101"})
+	if mt940.ErrorCode() != model.ErrorCodeMissingField || mt940.ParseError.Error() != "mark: missing value" {
+		t.Errorf("Unexpected mt940 error %s %v", mt940.ErrorCode(), mt940.ParseError)
+	}
+}
+
+func TestParser_CollectsFieldErrors(t *testing.T) {
+	record := map[string]string{"ext_id": "1", "type": "DEBIT", "amount": "ten", "date": "2025.01.01", "balance": "x"}
+
+	txn := NewDbsParser().Parse(record)
+
+	fieldErrors := txn.FieldErrors()
+	expected := []struct {
+		field string
+		value string
+		code  model.ErrorCode
+	}{
+		{"date", "2025.01.01", model.ErrorCodeInvalidDate},
+		{"amount", "ten", model.ErrorCodeInvalidAmount},
+		{"balance", "x", model.ErrorCodeInvalidAmount},
+	}
+
+	if len(fieldErrors) != len(expected) {
+		t.Fatalf("Expected %d field errors, got %v", len(expected), txn.ParseError)
+	}
+	for i, field := range expected {
+		if fieldErrors[i].Field != field.field || fieldErrors[i].Value != field.value || fieldErrors[i].Code != field.code {
+			t.Errorf("Expected %+v, got %+v", field, fieldErrors[i])
+		}
+	}
+
+	if message := txn.ParseError.Error(); !strings.HasPrefix(message, "date: ") || !strings.Contains(message, "; amount: ") || !strings.Contains(message, "; balance: ") {
+		t.Errorf("Expected every field in the message, got %q", message)
+	}
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+type Mt940Record struct {
+	StatementReference string `mapstructure:"statement_reference"`
+	Account            string `mapstructure:"account"`
+	ValueDate          string `mapstructure:"value_date"` // YYMMDD
+	Mark               string `mapstructure:"mark"`       // C, D, RC, RD
+	Amount             string `mapstructure:"amount"`     // 1000,50
+	TransactionType    string `mapstructure:"transaction_type"`
+	Reference          string `mapstructure:"reference"`
+	BankReference      string `mapstructure:"bank_reference"`
+	Narrative          string `mapstructure:"narrative"`
+	OpeningMark        string `mapstructure:"opening_mark"` // C, D
+	OpeningBalance     string `mapstructure:"opening_balance"`
+	ClosingMark        string `mapstructure:"closing_mark"` // C, D
+	ClosingBalance     string `mapstructure:"closing_balance"`
+}
+
+type Mt940Parser struct {
+	source string
+}
+
+// MT940 is shared by many banks, source names the bank the statement came from
+func NewMt940Parser(source string) *Mt940Parser {
+	return &Mt940Parser{source: source}
+}
+
+func (a *Mt940Parser) Parse(record map[string]string) model.Transaction {
+	var mt940 Mt940Record
+	var parseErrs model.TransactionErrors
+
+	if err := mapstructure.Decode(record, &mt940); err != nil {
+		parseErrs = append(parseErrs, &model.TransactionError{Code: model.ErrorCodeInvalidField, Err: err})
+	}
+
+	t, err := time.Parse("060102", mt940.ValueDate)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidDate, "value_date", mt940.ValueDate, err))
+	}
+
+	amountf64, err := strconv.ParseFloat(strings.Replace(mt940.Amount, ",", ".", 1), 64)
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "amount", mt940.Amount, err))
+	}
+
+	var txnType string
+	switch mt940.Mark {
+	case "C", "RD":
+		// reversal of debit credits the account
+		txnType = "CREDIT"
+	case "D", "RC":
+		// reversal of credit debits the account
+		txnType = "DEBIT"
+	default:
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidField, "mark", mt940.Mark, fmt.Errorf("invalid debit/credit mark %q", mt940.Mark)))
+	}
+
+	var balance model.StatementBalance
+	balance.StatementId = mt940.Account + "|" + mt940.StatementReference
+
+	balance.OpeningBalance, balance.HasOpening, err = parseBalance(mt940.OpeningBalance, mt940.OpeningMark == "D")
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "opening_balance", mt940.OpeningBalance, err))
+	}
+
+	balance.ClosingBalance, balance.HasClosing, err = parseBalance(mt940.ClosingBalance, mt940.ClosingMark == "D")
+	if err != nil {
+		parseErrs = append(parseErrs, fieldError(model.ErrorCodeInvalidAmount, "closing_balance", mt940.ClosingBalance, err))
+	}
+
+	// NONREF is used when the account owner gave no reference
+	id := mt940.Reference
+	if id == "" || id == "NONREF" {
+		id = mt940.BankReference
+	}
+
+	// a reversal repeats the customer reference of the entry it reverses
+	var reversalOf string
+	if (mt940.Mark == "RC" || mt940.Mark == "RD") && mt940.Reference != "NONREF" {
+		reversalOf = mt940.Reference
+	}
+
+	return model.Transaction{
+		Source:     a.source,
+		Id:         id,
+		Type:       txnType,
+		Amount:     amountf64,
+		Date:       t.Format("2006-01-02"),
+		DateEpoch:  t.UnixMilli(),
+		Reference:  mt940.BankReference,
+		Narrative:  mt940.Narrative,
+		ReversalOf: reversalOf,
+		Balance:    balance,
+		ParseError: parseErrs.Err(),
+	}
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+type TestMt940Parser_ParseArgs struct {
+	Label         string
+	Args          map[string]string
+	CheckExpected func(txn model.Transaction) error
+}
+
+func TestMt940Parser_Parse(t *testing.T) {
+	testCases := []TestMt940Parser_ParseArgs{{
+		Label: "match statement line",
+		Args: map[string]string{
+			"value_date":     "250101",
+			"mark":           "D",
+			"amount":         "4,05",
+			"reference":      "123",
+			"bank_reference": "BANKREF",
+			"narrative":      "REPAYMENT",
+		},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError != nil {
+				return fmt.Errorf("Expected nil, got %v", txn.ParseError)
+			}
+			if txn.Source != "dbs" {
+				return fmt.Errorf("Expected dbs, got %s", txn.Source)
+			}
+			if txn.Id != "123" {
+				return fmt.Errorf("Expected 123, got %s", txn.Id)
+			}
+			if txn.Type != "DEBIT" {
+				return fmt.Errorf("Expected DEBIT, got %s", txn.Type)
+			}
+			if txn.Amount != 4.05 {
+				return fmt.Errorf("Expected 4.05, got %.2f", txn.Amount)
+			}
+			if txn.Date != "2025-01-01" {
+				return fmt.Errorf("Expected 2025-01-01, got %s", txn.Date)
+			}
+			if txn.Reference != "BANKREF" {
+				return fmt.Errorf("Expected BANKREF, got %s", txn.Reference)
+			}
+			if txn.Narrative != "REPAYMENT" {
+				return fmt.Errorf("Expected REPAYMENT, got %s", txn.Narrative)
+			}
+			return nil
+		},
+	}, {
+		Label: "match statement balances",
+		Args: map[string]string{
+			"account":             "123",
+			"statement_reference": "STMT1",
+			"opening_mark":        "D",
+			"opening_balance":     "100,50",
+			"closing_mark":        "C",
+			"closing_balance":     "10,",
+		},
+		CheckExpected: func(txn model.Transaction) error {
+			balance := txn.Balance
+			if balance.StatementId != "123|STMT1" {
+				return fmt.Errorf("Expected 123|STMT1, got %s", balance.StatementId)
+			}
+			if !balance.HasOpening || balance.OpeningBalance != -100.5 {
+				return fmt.Errorf("Expected -100.50, got %.2f", balance.OpeningBalance)
+			}
+			if !balance.HasClosing || balance.ClosingBalance != 10 {
+				return fmt.Errorf("Expected 10.00, got %.2f", balance.ClosingBalance)
+			}
+			return nil
+		},
+	}, {
+		Label: "match id from bank reference",
+		Args:  map[string]string{"reference": "NONREF", "bank_reference": "BANKREF"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.Id != "BANKREF" {
+				return fmt.Errorf("Expected BANKREF, got %s", txn.Id)
+			}
+			return nil
+		},
+	}, {
+		Label: "match reversal type",
+		Args:  map[string]string{"mark": "RD"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.Type != "CREDIT" {
+				return fmt.Errorf("Expected CREDIT, got %s", txn.Type)
+			}
+			return nil
+		},
+	}, {
+		Label: "match reversal reference",
+		Args:  map[string]string{"mark": "RD", "reference": "cust_ref", "bank_reference": "BANKREF2"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ReversalOf != "cust_ref" {
+				return fmt.Errorf("Expected cust_ref, got %q", txn.ReversalOf)
+			}
+			return nil
+		},
+	}, {
+		Label: "error parsing mark",
+		Args:  map[string]string{"value_date": "250101", "amount": "1,00", "mark": "X"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError == nil {
+				return fmt.Errorf("Expected ParseError, got nil")
+			}
+			return nil
+		},
+	}, {
+		Label: "error parsing date",
+		Args:  map[string]string{"value_date": "2025-01-01", "amount": "1,00", "mark": "C"},
+		CheckExpected: func(txn model.Transaction) error {
+			if txn.ParseError == nil {
+				return fmt.Errorf("Expected ParseError, got nil")
+			}
+			if txn.Date != "0001-01-01" {
+				return fmt.Errorf("Expected 0001-01-01, got %s", txn.Date)
+			}
+			return nil
+		},
+	}}
+
+	newParser := NewMt940Parser("dbs")
+	for _, testCase := range testCases {
+		record := testCase.Args
+		txn := newParser.Parse(record)
+		err := testCase.CheckExpected(txn)
+		if err != nil {
+			t.Errorf("[%s] %v", testCase.Label, err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package parser
+
+import "strings"
+
+// virtualAccount returns the first of values set, without the spaces and dashes banks format VA numbers with
+func virtualAccount(values ...string) string {
+	for _, value := range values {
+		normalized := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(value))
+		if normalized != "" {
+			return normalized
+		}
+	}
+	return ""
+}
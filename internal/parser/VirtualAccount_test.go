@@ -0,0 +1,43 @@
+package parser
+
+import "testing"
+
+func TestParser_VirtualAccount(t *testing.T) {
+	testCases := []struct {
+		label    string
+		parse    func(record map[string]string) string
+		record   map[string]string
+		expected string
+	}{
+		{
+			label:    "amartha va_number",
+			parse:    func(record map[string]string) string { return NewAmarthaParser().Parse(record).VirtualAccount },
+			record:   map[string]string{"id": "1", "type": "CREDIT", "amount": "10", "date": "2025-01-01 10:00:00", "va_number": "8808 1234-5678"},
+			expected: "880812345678",
+		},
+		{
+			label:    "bca virtual_account",
+			parse:    func(record map[string]string) string { return NewBcaParser().Parse(record).VirtualAccount },
+			record:   map[string]string{"ext_id": "1", "amount": "10", "date": "2025-01-01", "virtual_account": " 880812345678 "},
+			expected: "880812345678",
+		},
+		{
+			label:    "dbs va_number first",
+			parse:    func(record map[string]string) string { return NewDbsParser().Parse(record).VirtualAccount },
+			record:   map[string]string{"ext_id": "1", "type": "CREDIT", "amount": "10", "date": "2025-01-01", "va_number": "111", "virtual_account": "222"},
+			expected: "111",
+		},
+		{
+			label:    "without virtual account",
+			parse:    func(record map[string]string) string { return NewDbsParser().Parse(record).VirtualAccount },
+			record:   map[string]string{"ext_id": "1", "type": "CREDIT", "amount": "10", "date": "2025-01-01"},
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if va := testCase.parse(testCase.record); va != testCase.expected {
+			t.Errorf("[%s] Expected %q, got %q", testCase.label, testCase.expected, va)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Aging of unmatched transactions, the age of a break is the number of days
+// between its Date and the as-of date of the run.
+
+type AgingBucket struct {
+	Label   string
+	MinDays int
+	MaxDays int // -1 has no upper bound
+}
+
+func DefaultAgingBuckets() []AgingBucket {
+	return []AgingBucket{
+		{Label: "0-1", MinDays: 0, MaxDays: 1},
+		{Label: "2-7", MinDays: 2, MaxDays: 7},
+		{Label: "8-30", MinDays: 8, MaxDays: 30},
+		{Label: "30+", MinDays: 31, MaxDays: -1},
+	}
+}
+
+// unknownAgingBucket holds breaks whose date cannot be read or fits no bucket
+const unknownAgingBucket = "unknown"
+
+type AgingConfig struct {
+	AsOf    time.Time
+	Buckets []AgingBucket // defaults to DefaultAgingBuckets
+}
+
+func (c AgingConfig) buckets() []AgingBucket {
+	if len(c.Buckets) == 0 {
+		return DefaultAgingBuckets()
+	}
+	return c.Buckets
+}
+
+// Validate checks buckets are in ascending order and do not overlap
+func (c AgingConfig) Validate() error {
+	buckets := c.buckets()
+	for i, bucket := range buckets {
+		if bucket.MinDays < 0 || (bucket.MaxDays != -1 && bucket.MaxDays < bucket.MinDays) {
+			return fmt.Errorf("invalid aging bucket %q", bucket.Label)
+		}
+		if i > 0 {
+			previous := buckets[i-1]
+			if previous.MaxDays == -1 || previous.MaxDays >= bucket.MinDays {
+				return fmt.Errorf("aging bucket %q overlaps %q", bucket.Label, previous.Label)
+			}
+		}
+	}
+	return nil
+}
+
+// Bucket returns the label of the bucket of a break dated date
+func (c AgingConfig) Bucket(date string) string {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return unknownAgingBucket
+	}
+
+	asOf := time.Date(c.AsOf.Year(), c.AsOf.Month(), c.AsOf.Day(), 0, 0, 0, 0, time.UTC)
+	// future dated breaks are as fresh as today's
+	days := max(int(asOf.Sub(t).Hours()/24), 0)
+
+	for _, bucket := range c.buckets() {
+		if days >= bucket.MinDays && (bucket.MaxDays == -1 || days <= bucket.MaxDays) {
+			return bucket.Label
+		}
+	}
+	return unknownAgingBucket
+}
+
+func (c AgingConfig) bucketOrder(label string) int {
+	for i, bucket := range c.buckets() {
+		if bucket.Label == label {
+			return i
+		}
+	}
+	return len(c.buckets())
+}
+
+type AgingRow struct {
+	Source string  `json:"source,omitempty"`
+	Type   string  `json:"type,omitempty"`
+	Bucket string  `json:"bucket"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// AgingReport counts unmatched transactions by source, type and bucket, safe for concurrent use
+type AgingReport struct {
+	config AgingConfig
+	mu     sync.Mutex
+	rows   map[[3]string]*AgingRow
+}
+
+func NewAgingReport(config AgingConfig) (*AgingReport, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &AgingReport{
+		config: config,
+		rows:   make(map[[3]string]*AgingRow),
+	}, nil
+}
+
+// Add counts t when it is an unmatched break, matched, self-cancelled and error records are ignored
+func (a *AgingReport) Add(t ReconTransaction) {
+	if !t.IsUnmatched() {
+		return
+	}
+
+	bucket := a.config.Bucket(t.Date)
+	key := [3]string{t.Source, t.Type, bucket}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	row, ok := a.rows[key]
+	if !ok {
+		row = &AgingRow{Source: t.Source, Type: t.Type, Bucket: bucket}
+		a.rows[key] = row
+	}
+	row.Count += 1
+	row.Amount += t.OpenAmount()
+}
+
+// Rows returns the report ordered by source, type and bucket
+func (a *AgingReport) Rows() []AgingRow {
+	a.mu.Lock()
+	rows := make([]AgingRow, 0, len(a.rows))
+	for _, row := range a.rows {
+		rows = append(rows, *row)
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(rows, func(x, y AgingRow) int {
+		return cmp.Or(
+			cmp.Compare(x.Source, y.Source),
+			cmp.Compare(x.Type, y.Type),
+			cmp.Compare(a.config.bucketOrder(x.Bucket), a.config.bucketOrder(y.Bucket)),
+		)
+	})
+
+	return rows
+}
+
+// Totals returns the rows summed by bucket, in bucket order
+func (a *AgingReport) Totals() []AgingRow {
+	totals := []AgingRow{}
+	for _, row := range a.Rows() {
+		i := slices.IndexFunc(totals, func(total AgingRow) bool { return total.Bucket == row.Bucket })
+		if i == -1 {
+			totals = append(totals, AgingRow{Bucket: row.Bucket})
+			i = len(totals) - 1
+		}
+		totals[i].Count += row.Count
+		totals[i].Amount += row.Amount
+	}
+
+	slices.SortFunc(totals, func(x, y AgingRow) int {
+		return cmp.Compare(a.config.bucketOrder(x.Bucket), a.config.bucketOrder(y.Bucket))
+	})
+
+	return totals
+}
+
+func (a *AgingReport) WriteCsv(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create aging report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"source", "type", "bucket", "count", "amount"})
+	for _, row := range a.Rows() {
+		writer.Write([]string{row.Source, row.Type, row.Bucket, strconv.Itoa(row.Count), fmt.Sprintf("%.2f", row.Amount)})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write aging report: %w", err)
+	}
+
+	return file.Close()
+}
+
+func (a *AgingReport) WriteJson(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create aging report: %w", err)
+	}
+	defer file.Close()
+
+	buckets := []string{}
+	for _, bucket := range a.config.buckets() {
+		buckets = append(buckets, bucket.Label)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(struct {
+		AsOf    string     `json:"as_of"`
+		Buckets []string   `json:"buckets"`
+		Rows    []AgingRow `json:"rows"`
+		Totals  []AgingRow `json:"totals"`
+	}{
+		AsOf:    a.config.AsOf.Format(time.DateOnly),
+		Buckets: buckets,
+		Rows:    a.Rows(),
+		Totals:  a.Totals(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write aging report: %w", err)
+	}
+
+	return file.Close()
+}
+
+func (r *ReconService) PassThroughAging(reconTransactionChan <-chan ReconTransaction, report *AgingReport) <-chan ReconTransaction {
+	return pipeline.TransformChan(r.Ctx, reconTransactionChan, func(t ReconTransaction) (ReconTransaction, bool) {
+		report.Add(t)
+		return t, true
+	}, r.StageOptions("aging")...)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func TestAgingConfig_Bucket(t *testing.T) {
+	config := AgingConfig{AsOf: time.Date(2025, 2, 1, 15, 0, 0, 0, time.UTC)}
+
+	testCases := map[string]string{
+		"2025-02-01": "0-1",
+		"2025-01-31": "0-1",
+		"2025-01-30": "2-7",
+		"2025-01-25": "2-7",
+		"2025-01-24": "8-30",
+		"2025-01-02": "8-30",
+		"2025-01-01": "30+",
+		"2025-02-05": "0-1",
+		"not a date": "unknown",
+	}
+
+	for date, expected := range testCases {
+		if bucket := config.Bucket(date); bucket != expected {
+			t.Errorf("[%s] Expected %s, got %s", date, expected, bucket)
+		}
+	}
+}
+
+func TestAgingConfig_Validate(t *testing.T) {
+	config := AgingConfig{Buckets: []AgingBucket{
+		{Label: "0-7", MinDays: 0, MaxDays: 7},
+		{Label: "5+", MinDays: 5, MaxDays: -1},
+	}}
+
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected overlapping buckets error")
+	}
+
+	if err := (AgingConfig{}).Validate(); err != nil {
+		t.Errorf("Expected default buckets to be valid, got %v", err)
+	}
+}
+
+func TestAgingReport(t *testing.T) {
+	report, err := NewAgingReport(AgingConfig{
+		AsOf: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Buckets: []AgingBucket{
+			{Label: "fresh", MinDays: 0, MaxDays: 3},
+			{Label: "stale", MinDays: 4, MaxDays: -1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rt := range []ReconTransaction{
+		{Transaction: model.Transaction{Source: "bca", Type: "DEBIT", Amount: 10, Date: "2025-01-01"}},
+		{Transaction: model.Transaction{Source: "bca", Type: "DEBIT", Amount: 5, Date: "2025-01-02"}},
+		{Transaction: model.Transaction{Source: "bca", Type: "DEBIT", Amount: 1, Date: "2025-02-01"}},
+		{Transaction: model.Transaction{Source: "amartha", Type: "CREDIT", Amount: 7, Date: "2025-01-31"}},
+		{Transaction: model.Transaction{Source: "bca", Type: "DEBIT", Amount: 99, Date: "2025-01-01"}, IsMatched: true},
+		{Transaction: model.Transaction{Source: "bca", Type: "DEBIT", Amount: 99, Date: "2025-01-01", ParseError: errors.New("invalid")}, IsError: true},
+	} {
+		report.Add(rt)
+	}
+
+	expectedRows := []AgingRow{
+		{Source: "amartha", Type: "CREDIT", Bucket: "fresh", Count: 1, Amount: 7},
+		{Source: "bca", Type: "DEBIT", Bucket: "fresh", Count: 1, Amount: 1},
+		{Source: "bca", Type: "DEBIT", Bucket: "stale", Count: 2, Amount: 15},
+	}
+	if rows := report.Rows(); !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("Expected %v, got %v", expectedRows, rows)
+	}
+
+	expectedTotals := []AgingRow{
+		{Bucket: "fresh", Count: 2, Amount: 8},
+		{Bucket: "stale", Count: 2, Amount: 15},
+	}
+	if totals := report.Totals(); !reflect.DeepEqual(totals, expectedTotals) {
+		t.Errorf("Expected %v, got %v", expectedTotals, totals)
+	}
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "aging.csv")
+	if err := report.WriteCsv(csvPath); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(csvPath)
+	expectedCsv := "source,type,bucket,count,amount\n" +
+		"amartha,CREDIT,fresh,1,7.00\n" +
+		"bca,DEBIT,fresh,1,1.00\n" +
+		"bca,DEBIT,stale,2,15.00\n"
+	if string(content) != expectedCsv {
+		t.Errorf("Expected %q, got %q", expectedCsv, string(content))
+	}
+
+	jsonPath := filepath.Join(dir, "aging.json")
+	if err := report.WriteJson(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ = os.ReadFile(jsonPath)
+	var output struct {
+		AsOf    string     `json:"as_of"`
+		Buckets []string   `json:"buckets"`
+		Rows    []AgingRow `json:"rows"`
+		Totals  []AgingRow `json:"totals"`
+	}
+	if err := json.Unmarshal(content, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	if output.AsOf != "2025-02-01" || !reflect.DeepEqual(output.Buckets, []string{"fresh", "stale"}) {
+		t.Errorf("Unexpected json header %s %v", output.AsOf, output.Buckets)
+	}
+	if !reflect.DeepEqual(output.Rows, expectedRows) || !reflect.DeepEqual(output.Totals, expectedTotals) {
+		t.Errorf("Unexpected json rows %v totals %v", output.Rows, output.Totals)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Statement balance verification
+//
+// Every transaction carrying statement balances is grouped by source and
+// statement (or source file when the statement has no id). Once the stream
+// ends each statement is checked:
+//   (1) opening balance + sum of signed amounts must equal the closing balance
+//   (2) running balances must follow each other in file order
+//   (3) a statement with unparseable rows cannot be trusted
+//
+// A failing statement indicates a truncated or corrupted file, reconciliation
+// results of that source should not be trusted until the file is fixed. A
+// SummaryAggregator given the report with WithBalanceCheck lists the source
+// under UntrustedSources, so every report carrying the summary shows it.
+
+const balanceTolerance = 0.005
+
+type StatementCheck struct {
+	Source           string
+	StatementId      string
+	OpeningBalance   float64
+	ClosingBalance   float64
+	ComputedClosing  float64
+	TransactionCount int
+	IsValid          bool
+	Remark           string
+}
+
+type BalanceCheckReport struct {
+	Statements []StatementCheck // set once the stream ends
+	statements map[string]*statementState
+	order      []string
+	mu         sync.Mutex // guards Statements, summaries read it from other stages
+}
+
+func NewBalanceCheckReport() *BalanceCheckReport {
+	return &BalanceCheckReport{
+		statements: make(map[string]*statementState),
+	}
+}
+
+// InvalidStatements returns the statements that failed verification
+func (b *BalanceCheckReport) InvalidStatements() []StatementCheck {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	invalid := []StatementCheck{}
+	for _, statement := range b.Statements {
+		if !statement.IsValid {
+			invalid = append(invalid, statement)
+		}
+	}
+	return invalid
+}
+
+type statementRow struct {
+	row            int
+	signedAmount   float64
+	runningBalance float64
+	hasRunning     bool
+}
+
+type statementState struct {
+	source         string
+	statementId    string
+	openingBalance float64
+	hasOpening     bool
+	closingBalance float64
+	hasClosing     bool
+	sum            float64
+	rows           []statementRow
+	errorCount     int
+}
+
+func (b *BalanceCheckReport) add(transaction model.Transaction) {
+	balance := transaction.Balance
+	if balance.IsEmpty() {
+		return
+	}
+
+	statementId := balance.StatementId
+	if statementId == "" {
+		statementId = transaction.SourceFile
+	}
+
+	key := transaction.Source + "|" + statementId
+	state, ok := b.statements[key]
+	if !ok {
+		state = &statementState{source: transaction.Source, statementId: statementId}
+		b.statements[key] = state
+		b.order = append(b.order, key)
+	}
+
+	if balance.HasOpening && !state.hasOpening {
+		state.openingBalance, state.hasOpening = balance.OpeningBalance, true
+	}
+
+	if balance.HasClosing && !state.hasClosing {
+		state.closingBalance, state.hasClosing = balance.ClosingBalance, true
+	}
+
+	if transaction.ParseError != nil {
+		state.errorCount += 1
+		return
+	}
+
+	state.sum += transaction.SignedAmount()
+	state.rows = append(state.rows, statementRow{
+		row:            transaction.SourceRow,
+		signedAmount:   transaction.SignedAmount(),
+		runningBalance: balance.RunningBalance,
+		hasRunning:     balance.HasRunning,
+	})
+}
+
+func (b *BalanceCheckReport) finish() {
+	statements := make([]StatementCheck, 0, len(b.order))
+	for _, key := range b.order {
+		statements = append(statements, b.statements[key].check())
+	}
+
+	b.mu.Lock()
+	b.Statements = statements
+	b.mu.Unlock()
+}
+
+func (s *statementState) check() StatementCheck {
+	result := StatementCheck{
+		Source:           s.source,
+		StatementId:      s.statementId,
+		OpeningBalance:   s.openingBalance,
+		ClosingBalance:   s.closingBalance,
+		TransactionCount: len(s.rows),
+		IsValid:          true,
+	}
+
+	if s.errorCount > 0 {
+		result.IsValid = false
+		result.Remark = fmt.Sprintf("statement contains %d unparseable rows", s.errorCount)
+		return result
+	}
+
+	sort.SliceStable(s.rows, func(i, j int) bool { return s.rows[i].row < s.rows[j].row })
+
+	if !s.hasOpening && len(s.rows) > 0 && s.rows[0].hasRunning {
+		// opening balance derived from the first running balance
+		result.OpeningBalance = s.rows[0].runningBalance - s.rows[0].signedAmount
+	}
+
+	// running balances, each row must continue from the previous one
+	balance, hasBalance := s.openingBalance, s.hasOpening
+	for _, row := range s.rows {
+		if !row.hasRunning {
+			hasBalance = false
+			continue
+		}
+
+		if hasBalance && math.Abs(balance+row.signedAmount-row.runningBalance) > balanceTolerance {
+			result.IsValid = false
+			result.Remark = fmt.Sprintf(
+				"running balance break at row %d, expected %.2f got %.2f",
+				row.row, balance+row.signedAmount, row.runningBalance,
+			)
+			return result
+		}
+
+		balance, hasBalance = row.runningBalance, true
+	}
+
+	if s.hasOpening {
+		result.ComputedClosing = s.openingBalance + s.sum
+	} else if len(s.rows) > 0 && s.rows[len(s.rows)-1].hasRunning {
+		result.ComputedClosing = s.rows[len(s.rows)-1].runningBalance
+	}
+
+	if !s.hasClosing {
+		result.ClosingBalance = result.ComputedClosing
+		return result
+	}
+
+	if !s.hasOpening && !hasBalance {
+		result.Remark = "closing balance cannot be verified without opening or running balance"
+		return result
+	}
+
+	if math.Abs(result.ComputedClosing-s.closingBalance) > balanceTolerance {
+		result.IsValid = false
+		result.Remark = fmt.Sprintf(
+			"closing balance mismatch, expected %.2f got %.2f",
+			result.ComputedClosing, s.closingBalance,
+		)
+	}
+
+	return result
+}
+
+// PassThroughBalanceCheck verifies statement balances, place it before any filtering so every row is counted
+func (r *ReconService) PassThroughBalanceCheck(transactionChan <-chan model.Transaction, report *BalanceCheckReport) <-chan model.Transaction {
+	outChan := make(chan model.Transaction, r.bufferSize)
+	metrics := r.metrics.Stage("balance_check")
+
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, metrics)
+			if !ok {
+				break
+			}
+
+			report.add(transaction)
+			if !pipeline.SendMeasured(r.Ctx, outChan, transaction, metrics) {
+				return context.Cause(r.Ctx)
+			}
+		}
+
+		if r.Ctx.Err() != nil {
+			return context.Cause(r.Ctx)
+		}
+
+		report.finish()
+		return nil
+	})
+
+	return outChan
+}
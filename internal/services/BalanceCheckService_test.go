@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+type TestReconService_PassThroughBalanceCheckArgs struct {
+	Label         string
+	Args          []model.Transaction
+	CheckExpected func(report *BalanceCheckReport) error
+}
+
+func TestReconService_PassThroughBalanceCheck(t *testing.T) {
+	ctx := context.Background()
+	statement := func(opening, closing float64) model.StatementBalance {
+		return model.StatementBalance{
+			StatementId:    "stmt_1",
+			OpeningBalance: opening,
+			HasOpening:     true,
+			ClosingBalance: closing,
+			HasClosing:     true,
+		}
+	}
+	running := func(balance float64) model.StatementBalance {
+		return model.StatementBalance{RunningBalance: balance, HasRunning: true}
+	}
+
+	testCases := []TestReconService_PassThroughBalanceCheckArgs{{
+		Label: "valid opening and closing",
+		Args: []model.Transaction{
+			{Source: "dbs", Type: "CREDIT", Amount: 10, Balance: statement(100, 106)},
+			{Source: "dbs", Type: "DEBIT", Amount: 4, Balance: statement(100, 106)},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			if len(report.Statements) != 1 {
+				return fmt.Errorf("Expected 1, got %d", len(report.Statements))
+			}
+			if len(report.InvalidStatements()) != 0 {
+				return fmt.Errorf("Expected valid, got %v", report.InvalidStatements())
+			}
+			if report.Statements[0].ComputedClosing != 106 {
+				return fmt.Errorf("Expected 106, got %.2f", report.Statements[0].ComputedClosing)
+			}
+			return nil
+		},
+	}, {
+		Label: "truncated statement",
+		Args: []model.Transaction{
+			{Source: "dbs", Type: "CREDIT", Amount: 10, Balance: statement(100, 106)},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			invalid := report.InvalidStatements()
+			if len(invalid) != 1 {
+				return fmt.Errorf("Expected 1 invalid, got %d", len(invalid))
+			}
+			if invalid[0].ComputedClosing != 110 {
+				return fmt.Errorf("Expected 110, got %.2f", invalid[0].ComputedClosing)
+			}
+			return nil
+		},
+	}, {
+		Label: "unparseable row",
+		Args: []model.Transaction{
+			{Source: "dbs", Type: "CREDIT", Amount: 10, Balance: statement(100, 110)},
+			{Source: "dbs", Balance: statement(100, 110), ParseError: fmt.Errorf("test parse error")},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			if len(report.InvalidStatements()) != 1 {
+				return fmt.Errorf("Expected 1 invalid, got %d", len(report.InvalidStatements()))
+			}
+			return nil
+		},
+	}, {
+		Label: "valid running balance out of order",
+		Args: []model.Transaction{
+			{Source: "bca", SourceFile: "bca.csv", SourceRow: 2, Type: "DEBIT", Amount: 4, Balance: running(106)},
+			{Source: "bca", SourceFile: "bca.csv", SourceRow: 1, Type: "CREDIT", Amount: 10, Balance: running(110)},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			if len(report.InvalidStatements()) != 0 {
+				return fmt.Errorf("Expected valid, got %v", report.InvalidStatements())
+			}
+			if report.Statements[0].OpeningBalance != 100 {
+				return fmt.Errorf("Expected 100, got %.2f", report.Statements[0].OpeningBalance)
+			}
+			if report.Statements[0].StatementId != "bca.csv" {
+				return fmt.Errorf("Expected bca.csv, got %s", report.Statements[0].StatementId)
+			}
+			return nil
+		},
+	}, {
+		Label: "running balance break",
+		Args: []model.Transaction{
+			{Source: "bca", SourceFile: "bca.csv", SourceRow: 1, Type: "CREDIT", Amount: 10, Balance: running(110)},
+			{Source: "bca", SourceFile: "bca.csv", SourceRow: 3, Type: "DEBIT", Amount: 4, Balance: running(100)},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			if len(report.InvalidStatements()) != 1 {
+				return fmt.Errorf("Expected 1 invalid, got %d", len(report.InvalidStatements()))
+			}
+			return nil
+		},
+	}, {
+		Label: "no balances skipped",
+		Args: []model.Transaction{
+			{Source: "amartha", Type: "CREDIT", Amount: 10},
+		},
+		CheckExpected: func(report *BalanceCheckReport) error {
+			if len(report.Statements) != 0 {
+				return fmt.Errorf("Expected 0, got %d", len(report.Statements))
+			}
+			return nil
+		},
+	}}
+
+	for _, testCase := range testCases {
+		newService, _ := NewReconService(NewReconServiceOpts{Ctx: ctx})
+		report := NewBalanceCheckReport()
+
+		inChan := make(chan model.Transaction, len(testCase.Args))
+		for _, txn := range testCase.Args {
+			inChan <- txn
+		}
+		close(inChan)
+
+		count := 0
+		for range newService.PassThroughBalanceCheck(inChan, report) {
+			count += 1
+		}
+
+		if count != len(testCase.Args) {
+			t.Errorf("[%s] Expected %d passed through, got %d", testCase.Label, len(testCase.Args), count)
+		}
+
+		if err := testCase.CheckExpected(report); err != nil {
+			t.Errorf("[%s] %v", testCase.Label, err)
+		}
+	}
+}
+
+func TestReconService_UntrustedSources(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "report.html")
+
+	newService, _ := NewReconService(NewReconServiceOpts{Ctx: context.Background()})
+	newService.internalSource = "amartha"
+
+	truncated := model.StatementBalance{StatementId: "stmt_1", OpeningBalance: 100, HasOpening: true, ClosingBalance: 106, HasClosing: true}
+	transactions := []model.Transaction{
+		{Source: "amartha", Id: "txn_1", Type: "CREDIT", Amount: 10, Date: "2025-01-01"},
+		{Source: "dbs", Id: "txn_1", Type: "CREDIT", Amount: 10, Date: "2025-01-01", Balance: truncated},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	report := NewBalanceCheckReport()
+	aggregator := NewSummaryAggregator(2).WithBalanceCheck(report)
+	if summary := aggregator.Snapshot(); len(summary.UntrustedSources) != 0 {
+		t.Errorf("Expected no untrusted source before the check ends, got %v", summary.UntrustedSources)
+	}
+
+	reconChan, err := newService.Reconcile(newService.PassThroughBalanceCheck(inChan, report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newService.WriteHtml(outputPath, reconChan, aggregator); err != nil {
+		t.Fatal(err)
+	}
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := aggregator.Snapshot()
+	expected := "stmt_1: closing balance mismatch, expected 110.00 got 106.00"
+	if statements := summary.UntrustedSources["dbs"]; len(statements) != 1 || statements[0] != expected {
+		t.Errorf("Expected dbs untrusted with %q, got %v", expected, summary.UntrustedSources)
+	}
+	if _, ok := summary.UntrustedSources["amartha"]; ok {
+		t.Errorf("Expected amartha trusted, got %v", summary.UntrustedSources)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(content)
+
+	for _, expected := range []string{
+		`<li>dbs ` + expected + `</li>`,
+		`<td>dbs <span class="muted">external</span> <strong class="untrusted">untrusted</strong></td>`,
+		`<td>amartha <span class="muted">internal</span></td>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %s in report", expected)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+// Full reconciliation report, one row per matched pair, unmatched transaction,
+// self-cancelled reversal leg or error record. Matched pairs are written side
+// by side, internal first, with the file and row of both sides so every line
+// can be traced back.
+
+const (
+	ReportStatusMatched       = "MATCHED"
+	ReportStatusUnmatched     = "UNMATCHED"
+	ReportStatusError         = "ERROR"
+	ReportStatusSelfCancelled = "SELF_CANCELLED"
+)
+
+var fullReportHeader = []string{
+	"status", "match_rule",
+	"internal_source", "internal_id", "internal_type", "internal_amount", "internal_date", "internal_file", "internal_row",
+	"external_source", "external_id", "external_type", "external_amount", "external_date", "external_file", "external_row",
+	"difference", "remark", "error_code",
+}
+
+// WriteFullReportCsv writes every reconciled transaction, feed it the Reconcile output without FilterMismatched
+func (r *ReconService) WriteFullReportCsv(filepath string, reconTransactionChan <-chan ReconTransaction) error {
+	return r.writeCsv(filepath, fullReportHeader, reconTransactionChan, r.fullReportRecord)
+}
+
+func (r *ReconService) fullReportRecord(rt ReconTransaction) map[string]string {
+	record := map[string]string{
+		"match_rule": rt.MatchRule,
+		"remark":     rt.Remark,
+		"error_code": errorCodes(rt.Transaction),
+	}
+
+	switch {
+	case rt.IsError:
+		record["status"] = ReportStatusError
+	case rt.IsMatched:
+		record["status"] = ReportStatusMatched
+	case rt.IsSelfCancelled:
+		// both legs share a source, each is written on its own row like a break
+		record["status"] = ReportStatusSelfCancelled
+	default:
+		record["status"] = ReportStatusUnmatched
+	}
+
+	internal, external := rt.Transaction, rt.OtherTransaction
+	if rt.Source != r.internalSource {
+		internal, external = rt.OtherTransaction, rt.Transaction
+	}
+
+	if rt.IsMatched || rt.Source == r.internalSource {
+		addReportSide(record, "internal", internal)
+	}
+	if rt.IsMatched || rt.Source != r.internalSource {
+		addReportSide(record, "external", external)
+	}
+
+	if rt.IsMatched {
+		// internal minus external
+		record["difference"] = fmt.Sprintf("%.2f", pairDifference(rt, internal, external))
+	}
+
+	return record
+}
+
+func addReportSide(record map[string]string, side string, t model.Transaction) {
+	record[side+"_source"] = t.Source
+	record[side+"_id"] = t.Id
+	record[side+"_type"] = t.Type
+	record[side+"_amount"] = fmt.Sprintf("%.2f", t.Amount)
+	record[side+"_date"] = t.Date
+	record[side+"_file"] = t.SourceFile
+
+	if t.SourceRow > 0 {
+		record[side+"_row"] = strconv.Itoa(t.SourceRow)
+	}
+}
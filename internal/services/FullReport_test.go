@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+)
+
+func TestReconService_WriteFullReportCsv(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "full.csv")
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         context.Background(),
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+	newService.internalSource = "amartha"
+
+	transactions := []model.Transaction{
+		{Source: "amartha", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01", SourceFile: "amartha.csv", SourceRow: 1},
+		{Source: "bca", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01", SourceFile: "bca.csv", SourceRow: 1},
+		{Source: "bca", Id: "bca_1", Type: "CREDIT", Amount: 7, Date: "2025-01-01", SourceFile: "bca.csv", SourceRow: 2},
+		{Source: "amartha", Id: "amartha_1", Type: "CREDIT", Amount: 7, Date: "2025-01-01", SourceFile: "amartha.csv", SourceRow: 2},
+		{Source: "amartha", Id: "amartha_2", Type: "DEBIT", Amount: 3, Date: "2025-01-02", SourceFile: "amartha.csv", SourceRow: 3},
+		{Source: "dbs", Id: "dbs_1", Type: "DEBIT", Amount: 2.5, Date: "2025-01-02", SourceFile: "dbs.csv", SourceRow: 1},
+		{Source: "dbs", Id: "dbs_2", Type: "CREDIT", Amount: 1, Date: "2025-01-03", SourceFile: "dbs.csv", SourceRow: 2},
+		{Source: "dbs", Id: "dbs_3", SourceFile: "dbs.csv", SourceRow: 3, ParseError: model.TransactionErrors{
+			{Code: model.ErrorCodeInvalidDate, Field: "date", Value: "2025.01.03", Err: errors.New("cannot parse")},
+			{Code: model.ErrorCodeInvalidAmount, Field: "amount", Value: "ten", Err: errors.New("invalid syntax")},
+		}},
+		{Source: "dbs", Id: "dbs_2", Type: "CREDIT", Amount: 1, Date: "2025-01-03", SourceFile: "dbs.csv", SourceRow: 4},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newService.WriteFullReportCsv(outputPath, reconChan); err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := os.Open(outputPath)
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(rows[0], fullReportHeader) {
+		t.Fatalf("Expected header %v, got %v", fullReportHeader, rows[0])
+	}
+
+	lines := []string{}
+	for _, row := range rows[1:] {
+		lines = append(lines, strings.Join(row, ","))
+	}
+	slices.Sort(lines)
+
+	expected := []string{
+		"ERROR,,,,,,,,,dbs,dbs_2,CREDIT,1.00,2025-01-03,dbs.csv,4,,id: duplicate of an unmatched transaction with the same date and type,DUPLICATE",
+		"ERROR,,,,,,,,,dbs,dbs_3,,0.00,,dbs.csv,3,,date: cannot parse; amount: invalid syntax,INVALID_DATE;INVALID_AMOUNT",
+		"MATCHED,AMOUNT,amartha,amartha_1,CREDIT,7.00,2025-01-01,amartha.csv,2,bca,bca_1,CREDIT,7.00,2025-01-01,bca.csv,2,0.00,,",
+		"MATCHED,DATE,amartha,amartha_2,DEBIT,3.00,2025-01-02,amartha.csv,3,dbs,dbs_1,DEBIT,2.50,2025-01-02,dbs.csv,1,0.50,,",
+		"MATCHED,ID,amartha,by_id,DEBIT,10.00,2025-01-01,amartha.csv,1,bca,by_id,DEBIT,10.00,2025-01-01,bca.csv,1,0.00,,",
+		"UNMATCHED,,,,,,,,,dbs,dbs_2,CREDIT,1.00,2025-01-03,dbs.csv,2,,No matching internal transaction found,",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
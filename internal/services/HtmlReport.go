@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Static HTML report of one run, meant to be emailed to ops managers.
+//
+// The page is a single file: styles, script and data are inlined, nothing is
+// fetched when it is opened. It holds summary cards, a per-source breakdown,
+// the aging of unmatched breaks, a histogram of matched pair discrepancies and
+// searchable tables of mismatches, errors and discrepancies. Clicking a source
+// filters the tables by it.
+//
+// The tables are held in memory until the stream is drained, each is capped
+// at htmlReportMaxRows rows so the page stays small enough to open and send.
+
+//go:embed templates/report.html
+var htmlReportTemplate string
+
+const htmlReportMaxRows = 5000
+
+// upper bounds of the discrepancy histogram bins, the last bin is open-ended
+var htmlDiscrepancyBins = []float64{1, 10, 100, 1000, 10000}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"amount": formatAmount,
+}).Parse(htmlReportTemplate))
+
+type htmlReportData struct {
+	GeneratedAt   string
+	Summary       ReconSummary
+	Sources       []htmlSourceRow
+	Aging         []htmlAgingRow
+	Histogram     []htmlHistogramBin
+	Mismatches    htmlTable
+	Errors        htmlTable
+	Discrepancies htmlTable
+}
+
+type htmlSourceRow struct {
+	SummaryBreakdown
+	Source    string
+	Side      string
+	Untrusted bool // a statement of the source failed balance verification
+}
+
+type htmlAgingRow struct {
+	Bucket string
+	Count  int
+	Amount float64
+}
+
+type htmlHistogramBin struct {
+	Label   string
+	Count   int
+	Percent float64 // of the largest bin, the bar width
+}
+
+type htmlTable struct {
+	Rows  []htmlTransactionRow
+	Total int // rows seen, more than len(Rows) once capped
+}
+
+func (t *htmlTable) add(row htmlTransactionRow) {
+	t.Total += 1
+	if len(t.Rows) < htmlReportMaxRows {
+		t.Rows = append(t.Rows, row)
+	}
+}
+
+type htmlTransactionRow struct {
+	Source      string
+	Id          string
+	Type        string
+	Amount      float64
+	Date        string
+	File        string
+	Row         int
+	ErrorCode   string // set on errors
+	OtherSource string // set on discrepancies
+	OtherId     string
+	OtherAmount float64
+	Difference  float64
+	Remark      string
+}
+
+// WriteHtml drains reconTransactionChan into a self-contained HTML report at
+// filepath. Every transaction is added to aggregator, whose snapshot fills the
+// summary, nil counts into a fresh one. It takes the same stream as WriteFullReportCsv.
+func (r *ReconService) WriteHtml(filepath string, reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) error {
+	if aggregator == nil {
+		aggregator = NewSummaryAggregator(1)
+	}
+
+	if err := r.writeHtml(filepath, reconTransactionChan, aggregator); err != nil {
+		r.group.Fail(err)
+	}
+
+	// see writeCsv
+	return r.group.Err()
+}
+
+func (r *ReconService) writeHtml(filepath string, reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) error {
+	data := htmlReportData{}
+	histogram := make([]int, len(htmlDiscrepancyBins)+1)
+
+	metrics := r.metrics.Stage("write_html")
+	for {
+		rt, ok := pipeline.Receive(r.Ctx, reconTransactionChan, metrics)
+		if !ok {
+			break
+		}
+
+		aggregator.Add(rt)
+		r.addHtmlRow(&data, histogram, rt)
+	}
+
+	if err := context.Cause(r.Ctx); err != nil {
+		return err
+	}
+
+	data.GeneratedAt = time.Now().Format(time.DateTime)
+	data.Summary = aggregator.Snapshot()
+	data.Sources = r.htmlSources(data.Summary)
+	data.Aging = htmlAging(data.Summary, aggregator.aging)
+	data.Histogram = htmlHistogram(histogram)
+
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create html report: %w", err)
+	}
+	defer file.Close()
+
+	if err := htmlReport.Execute(file, data); err != nil {
+		return fmt.Errorf("failed to write html report: %w", err)
+	}
+
+	return file.Close()
+}
+
+func (r *ReconService) addHtmlRow(data *htmlReportData, histogram []int, rt ReconTransaction) {
+	row := htmlTransactionRow{
+		Source: rt.Source,
+		Id:     rt.Id,
+		Type:   rt.Type,
+		Amount: rt.Amount,
+		Date:   rt.Date,
+		File:   rt.SourceFile,
+		Row:    rt.SourceRow,
+		Remark: rt.Remark,
+	}
+
+	switch {
+	case rt.IsSelfCancelled:
+		// netted within their source, counted in the summary only
+		return
+
+	case rt.IsError:
+		row.ErrorCode = errorCodes(rt.Transaction)
+		data.Errors.add(row)
+
+	case rt.IsMatched:
+		internal, external := rt.Transaction, rt.OtherTransaction
+		if rt.Source != r.internalSource {
+			internal, external = rt.OtherTransaction, rt.Transaction
+		}
+
+		difference := pairDifference(rt, internal, external)
+		if math.Abs(difference) < discrepancyTolerance {
+			return
+		}
+
+		bin, _ := slices.BinarySearch(htmlDiscrepancyBins, math.Abs(difference))
+		histogram[bin] += 1
+
+		data.Discrepancies.add(htmlTransactionRow{
+			Source:      internal.Source,
+			Id:          internal.Id,
+			Type:        internal.Type,
+			Amount:      internal.Amount,
+			Date:        internal.Date,
+			File:        internal.SourceFile,
+			Row:         internal.SourceRow,
+			OtherSource: external.Source,
+			OtherId:     external.Id,
+			OtherAmount: external.Amount,
+			Difference:  difference,
+			Remark:      rt.MatchRule,
+		})
+
+	default:
+		data.Mismatches.add(row)
+	}
+}
+
+func (r *ReconService) htmlSources(summary ReconSummary) []htmlSourceRow {
+	rows := []htmlSourceRow{}
+	for _, source := range slices.Sorted(maps.Keys(summary.BySource)) {
+		side := ManifestSideExternal
+		if source == r.internalSource {
+			side = ManifestSideInternal
+		}
+
+		_, untrusted := summary.UntrustedSources[source]
+		rows = append(rows, htmlSourceRow{SummaryBreakdown: summary.BySource[source], Source: source, Side: side, Untrusted: untrusted})
+	}
+	return rows
+}
+
+func htmlAging(summary ReconSummary, config *AgingConfig) []htmlAgingRow {
+	if config == nil {
+		return nil
+	}
+
+	rows := []htmlAgingRow{}
+	for _, bucket := range config.buckets() {
+		rows = append(rows, htmlAgingRow{
+			Bucket: bucket.Label,
+			Count:  summary.AgingCountByBucket[bucket.Label],
+			Amount: summary.AgingAmountByBucket[bucket.Label],
+		})
+	}
+	if count := summary.AgingCountByBucket[unknownAgingBucket]; count > 0 {
+		rows = append(rows, htmlAgingRow{
+			Bucket: unknownAgingBucket,
+			Count:  count,
+			Amount: summary.AgingAmountByBucket[unknownAgingBucket],
+		})
+	}
+	return rows
+}
+
+func htmlHistogram(counts []int) []htmlHistogramBin {
+	largest := slices.Max(counts)
+
+	bins := make([]htmlHistogramBin, len(counts))
+	for i, count := range counts {
+		switch {
+		case i == 0:
+			bins[i].Label = fmt.Sprintf("≤ %s", formatAmount(htmlDiscrepancyBins[0]))
+		case i == len(htmlDiscrepancyBins):
+			bins[i].Label = fmt.Sprintf("> %s", formatAmount(htmlDiscrepancyBins[i-1]))
+		default:
+			bins[i].Label = fmt.Sprintf("%s - %s", formatAmount(htmlDiscrepancyBins[i-1]), formatAmount(htmlDiscrepancyBins[i]))
+		}
+
+		bins[i].Count = count
+		if largest > 0 {
+			bins[i].Percent = float64(count) * 100 / float64(largest)
+		}
+	}
+	return bins
+}
+
+// formatAmount writes amount with two decimals and thousands separators
+func formatAmount(amount float64) string {
+	formatted := fmt.Sprintf("%.2f", math.Abs(amount))
+	whole, decimals, _ := strings.Cut(formatted, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if amount < 0 && formatted != "0.00" {
+		return "-" + grouped.String() + "." + decimals
+	}
+	return grouped.String() + "." + decimals
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+)
+
+func TestFormatAmount(t *testing.T) {
+	testCases := map[float64]string{
+		0:           "0.00",
+		-0.001:      "0.00",
+		12.5:        "12.50",
+		999.999:     "1,000.00",
+		1234567.891: "1,234,567.89",
+		-98765.4:    "-98,765.40",
+	}
+
+	for amount, expected := range testCases {
+		if formatted := formatAmount(amount); formatted != expected {
+			t.Errorf("[%v] Expected %s, got %s", amount, expected, formatted)
+		}
+	}
+}
+
+func TestReconService_WriteHtml(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "report.html")
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         context.Background(),
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+	newService.internalSource = "amartha"
+
+	transactions := []model.Transaction{
+		{Source: "amartha", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01"},
+		{Source: "bca", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01"},
+		{Source: "amartha", Id: "amartha_2", Type: "DEBIT", Amount: 3050, Date: "2025-01-02"},
+		{Source: "dbs", Id: "dbs_1", Type: "DEBIT", Amount: 1000, Date: "2025-01-02"},
+		{Source: "amartha", Id: "<script>alert(1)</script>", Type: "CREDIT", Amount: 4, Date: "2025-01-03", SourceFile: "amartha.csv", SourceRow: 3},
+		{Source: "dbs", Id: "dbs_3", SourceFile: "dbs.csv", SourceRow: 3, ParseError: errors.New("invalid amount")},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator, _ := NewSummaryAggregator(1).WithAging(AgingConfig{AsOf: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)})
+	if err := newService.WriteHtml(outputPath, reconChan, aggregator); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(content)
+
+	for _, expected := range []string{
+		`<div class="muted">Processed transactions</div><div class="value">6</div>`,
+		`<div class="muted">Matched pairs</div><div class="value">2</div>`,
+		`<div class="muted">Unmatched</div><div class="value">1</div>`,
+		`<div class="muted">Unmatched amount</div><div class="value">4.00</div>`,
+		`<div class="muted">Errors</div><div class="value">1</div>`,
+		`<div class="value">2,050.00</div>`,
+		`transactions from 2025-01-01 to 2025-01-03`,
+		`<tr class="source" data-source="dbs"><td>dbs <span class="muted">external</span></td><td class="num">1</td><td class="num">1,000.00</td><td class="num">0</td><td class="num">0.00</td><td class="num">0</td><td class="num">1</td></tr>`,
+		`<tr><td>DEBIT</td><td class="num">4</td><td class="num">4,070.00</td><td class="num">0</td><td class="num">0.00</td><td class="num">0</td><td class="num">0</td></tr>`,
+		`<tr><td>DATE</td><td class="num">1</td></tr>`,
+		`<tr><td>0-1</td><td class="num">1</td><td class="num">4.00</td></tr>`,
+		`<tr><td>1,000.00 - 10,000.00</td><td class="num">1</td><td><div class="bar" style="width: 100.0%"></div></td></tr>`,
+		`<td>DATE</td><td>amartha</td><td>amartha_2</td>`,
+		`<td>&lt;script&gt;alert(1)&lt;/script&gt;</td>`,
+		`<td>invalid amount</td>`,
+		`Mismatches (1)`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %s in report", expected)
+		}
+	}
+
+	// nothing may be fetched when the report is opened
+	for _, external := range []string{"src=", "href=", "@import", "url("} {
+		if strings.Contains(page, external) {
+			t.Errorf("Expected no external asset, found %s", external)
+		}
+	}
+
+	if summary := aggregator.Snapshot(); summary.TotalMatched != 2 {
+		t.Errorf("Expected the report to fill the aggregator, got %+v", summary)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/storage"
+)
+
+// matcher holds the unmatched transactions of one reconciliation, matched
+// transactions are removed from its tables. It is not safe for concurrent use,
+// sharded reconciliation runs one matcher per shard.
+
+var errDuplicateTransaction = errors.New("duplicate of an unmatched transaction with the same date and type")
+
+type matcher struct {
+	internalSource string
+	internalTable  storage.ITable
+	externalTable  storage.ITable
+	partial        bool // cover internal transactions with several external ones once the input ends, see matchPartial
+
+	virtualAccounts *virtualAccountIndex
+	splitLegs       map[string][]model.Transaction // external legs sharing the date, type and id of one in externalTable, see isSplitLeg
+}
+
+func newMatcher(internalSource string) *matcher {
+	return newMatcherWithTables(internalSource, storage.NewHashTable(), storage.NewHashTable())
+}
+
+func newMatcherWithTables(internalSource string, internalTable storage.ITable, externalTable storage.ITable) *matcher {
+	return &matcher{
+		internalSource: internalSource,
+		internalTable:  internalTable,
+		externalTable:  externalTable,
+
+		virtualAccounts: newVirtualAccountIndex(0),
+		splitLegs:       make(map[string][]model.Transaction),
+	}
+}
+
+// err returns the first failure of either table, a failed lookup counts as no match
+func (m *matcher) err() error {
+	return errors.Join(m.internalTable.Err(), m.externalTable.Err())
+}
+
+// match stores transaction and matches it against the other side, ok is false when nothing matched yet.
+// A transaction with the date, type and id of one still unmatched on its side is
+// returned as a DUPLICATE error instead of replacing it.
+//
+// The check lives here rather than in the parsers, they parse one record at a
+// time over parallel workers while the side tables already index every
+// unmatched transaction by date, type and id. An open item carried forward is
+// not a duplicate, it is the same transaction read again by a re-run or an
+// overlapping input, so the row read keeps its place and the carried copy is dropped.
+// With partial matching an external leg of a split payment is held for matchPartial instead.
+func (m *matcher) match(transaction model.Transaction) (ReconTransaction, bool) {
+	isInternal := transaction.Source == m.internalSource
+	table := m.externalTable
+	if isInternal {
+		table = m.internalTable
+	}
+
+	hashKey := transaction.GetHashById()
+	if existing := table.GetById(hashKey); existing != nil {
+		switch {
+		case transaction.CarriedForward:
+			return ReconTransaction{}, false
+		case existing.(model.Transaction).CarriedForward:
+			// the row read replaces its carried copy
+		case !isInternal && m.isSplitLeg(existing.(model.Transaction), transaction):
+			m.splitLegs[hashKey] = append(m.splitLegs[hashKey], transaction)
+			return ReconTransaction{}, false
+		default:
+			transaction.ParseError = &model.TransactionError{Code: model.ErrorCodeDuplicate, Field: "id", Value: transaction.Id, Err: errDuplicateTransaction}
+			return errorReconTransaction(transaction), true
+		}
+	}
+
+	table.Put(transaction)
+
+	var reconTransaction ReconTransaction
+	var ok bool
+	if isInternal {
+		reconTransaction, ok = m.processInternalMatching(transaction)
+	} else {
+		reconTransaction, ok = m.processExternalMatching(transaction)
+	}
+
+	if !ok {
+		m.virtualAccounts.add(isInternal, transaction)
+	}
+	return reconTransaction, ok
+}
+
+// reconcileRemaining matches what is left once every transaction is read, returns false when emit was cancelled
+func (m *matcher) reconcileRemaining(emit func(ReconTransaction) bool) bool {
+	if m.partial && !m.matchPartial(emit) {
+		return false
+	}
+
+	emitted := true
+
+	// split legs left by partial matching are more transactions of their date and type
+	heldDates := make(map[string]bool)
+	for _, legs := range m.splitLegs {
+		for _, leg := range legs {
+			heldDates[strings.Join(leg.GetKeySearchByDate(), "|")] = true
+		}
+	}
+
+	m.externalTable.Each(func(externalTransaction storage.IHashable) bool {
+		// Last matching by date, if contains exactly one transaction
+		transaction := externalTransaction.(model.Transaction)
+
+		key, isMatch := m.internalTable.IsPathContainsOneValue(transaction.GetKeySearchByDate())
+		if isMatch && heldDates[strings.Join(transaction.GetKeySearchByDate(), "|")] {
+			isMatch = false
+		}
+		if isMatch {
+			_, isMatch = m.externalTable.IsPathContainsOneValue(transaction.GetKeySearchByDate())
+		}
+
+		reconTransaction := ReconTransaction{
+			Transaction: transaction,
+			IsMatched:   false,
+			Remark:      "No matching internal transaction found",
+		}
+
+		if isMatch {
+			// match exactly one transaction in internal and external by date, flag as match
+			intTransaction := m.internalTable.GetById(key)
+			m.internalTable.Remove(intTransaction)
+			m.externalTable.Remove(transaction)
+
+			reconTransaction = ReconTransaction{
+				Transaction:      transaction,
+				OtherTransaction: intTransaction.(model.Transaction),
+				IsMatched:        true,
+				MatchRule:        MatchRuleDate,
+			}
+		}
+
+		emitted = emit(reconTransaction)
+		return emitted
+	})
+
+	if !emitted {
+		return false
+	}
+
+	for _, hashKey := range slices.Sorted(maps.Keys(m.splitLegs)) {
+		for _, leg := range m.splitLegs[hashKey] {
+			if !emit(ReconTransaction{Transaction: leg, Remark: "No matching internal transaction found"}) {
+				return false
+			}
+		}
+	}
+	clear(m.splitLegs)
+
+	m.internalTable.Each(func(internalTransaction storage.IHashable) bool {
+		emitted = emit(ReconTransaction{
+			Transaction: internalTransaction.(model.Transaction),
+			IsMatched:   false,
+			Remark:      "No matching external transaction found",
+		})
+		return emitted
+	})
+
+	return emitted
+}
+
+// isSplitLeg reports whether leg, an external with the date, type and id of the unmatched existing one,
+// is another leg of a split payment rather than a duplicate. Only with partial matching, when its amount
+// differs or when the unmatched internal of that id still has room for every leg.
+func (m *matcher) isSplitLeg(existing model.Transaction, leg model.Transaction) bool {
+	if !m.partial {
+		return false
+	}
+	if math.Abs(existing.Amount-leg.Amount) >= discrepancyTolerance {
+		return true
+	}
+
+	internal := m.internalTable.GetById(leg.GetHashById())
+	if internal == nil {
+		return false
+	}
+
+	covered := existing.Amount + leg.Amount
+	for _, held := range m.splitLegs[leg.GetHashById()] {
+		covered += held.Amount
+	}
+	return covered < internal.(model.Transaction).Amount+discrepancyTolerance
+}
+
+// isPartialShare reports whether external, found by id, only covers part of internal,
+// the pair is then left to matchPartial rather than matched by id with a discrepancy
+func (m *matcher) isPartialShare(internal model.Transaction, external model.Transaction) bool {
+	return m.partial && external.Amount < internal.Amount-discrepancyTolerance
+}
+
+func (m *matcher) processInternalMatching(transaction model.Transaction) (ReconTransaction, bool) {
+	matchRule := MatchRuleId
+	extTransaction := m.externalTable.GetById(transaction.GetHashById())
+
+	if extTransaction != nil && m.isPartialShare(transaction, extTransaction.(model.Transaction)) {
+		return ReconTransaction{}, false
+	}
+
+	if extTransaction == nil {
+		matchRule = MatchRuleVirtualAccount
+		extTransaction = m.virtualAccounts.take(false, transaction, m.externalTable)
+	}
+
+	if extTransaction == nil {
+		matchRule = MatchRuleAmount
+		extTransaction = m.externalTable.GetFirstMatchByPath(transaction.GetKeySearchByAmount())
+	}
+
+	if extTransaction == nil {
+		// No match found
+		return ReconTransaction{}, false
+	}
+
+	// Matched and remove
+	m.internalTable.Remove(transaction)
+	m.externalTable.Remove(extTransaction)
+
+	return ReconTransaction{
+		Transaction:      transaction,
+		OtherTransaction: extTransaction.(model.Transaction),
+		IsMatched:        true,
+		MatchRule:        matchRule,
+	}, true
+}
+
+func (m *matcher) processExternalMatching(transaction model.Transaction) (ReconTransaction, bool) {
+	matchRule := MatchRuleId
+	intTransaction := m.internalTable.GetById(transaction.GetHashById())
+
+	if intTransaction != nil && m.isPartialShare(intTransaction.(model.Transaction), transaction) {
+		return ReconTransaction{}, false
+	}
+
+	if intTransaction == nil {
+		matchRule = MatchRuleVirtualAccount
+		intTransaction = m.virtualAccounts.take(true, transaction, m.internalTable)
+	}
+
+	if intTransaction == nil {
+		matchRule = MatchRuleAmount
+		intTransaction = m.internalTable.GetFirstMatchByPath(transaction.GetKeySearchByAmount())
+	}
+
+	if intTransaction == nil {
+		// No match found
+		return ReconTransaction{}, false
+	}
+
+	// Matched and remove
+	m.internalTable.Remove(intTransaction)
+	m.externalTable.Remove(transaction)
+
+	return ReconTransaction{
+		Transaction:      transaction,
+		OtherTransaction: intTransaction.(model.Transaction),
+		IsMatched:        true,
+		MatchRule:        matchRule,
+	}, true
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/storage"
+)
+
+func reconcileResults(t *testing.T, opts NewReconServiceOpts, transactions []model.Transaction) []string {
+	opts.Ctx = context.Background()
+	newService, _ := NewReconService(opts)
+	newService.internalSource = "internal"
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	outChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []string{}
+	for rt := range outChan {
+		results = append(results, fmt.Sprintf("%s|%s|%t|%t|%s|%s", rt.Source, rt.Id, rt.IsMatched, rt.IsError, rt.OtherTransaction.Id, rt.Remark))
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(results)
+	return results
+}
+
+func reconcileTestTransactions() []model.Transaction {
+	transactions := []model.Transaction{}
+
+	for day := 1; day <= 28; day++ {
+		date := fmt.Sprintf("2025-01-%02d", day)
+
+		// matched by id
+		transactions = append(transactions,
+			model.Transaction{Source: "internal", Id: "id-" + date, Type: "DEBIT", Amount: 10, Date: date},
+			model.Transaction{Source: "bca", Id: "id-" + date, Type: "DEBIT", Amount: 10, Date: date},
+		)
+
+		// matched by amount
+		transactions = append(transactions,
+			model.Transaction{Source: "bca", Id: "amount-ext-" + date, Type: "CREDIT", Amount: float64(day), Date: date},
+			model.Transaction{Source: "internal", Id: "amount-int-" + date, Type: "CREDIT", Amount: float64(day), Date: date},
+		)
+
+		if day%2 == 0 {
+			// matched by date only, one transaction left on each side
+			transactions = append(transactions,
+				model.Transaction{Source: "internal", Id: "date-int-" + date, Type: "DEBIT", Amount: 100, Date: date},
+				model.Transaction{Source: "dbs", Id: "date-ext-" + date, Type: "DEBIT", Amount: 101, Date: date},
+			)
+		} else {
+			// unmatched
+			transactions = append(transactions,
+				model.Transaction{Source: "internal", Id: "none-int-" + date, Type: "DEBIT", Amount: 100, Date: date},
+				model.Transaction{Source: "internal", Id: "none-int2-" + date, Type: "DEBIT", Amount: 200, Date: date},
+				model.Transaction{Source: "dbs", Id: "none-ext-" + date, Type: "CREDIT", Amount: 300, Date: date},
+			)
+		}
+	}
+
+	transactions = append(transactions, model.Transaction{Source: "bca", Id: "error", ParseError: errors.New("invalid amount")})
+
+	return transactions
+}
+
+func TestReconService_ReconcileSharded(t *testing.T) {
+	transactions := reconcileTestTransactions()
+
+	expected := reconcileResults(t, NewReconServiceOpts{}, transactions)
+	for _, shardCount := range []int{2, 4, 7} {
+		results := reconcileResults(t, NewReconServiceOpts{ShardCount: shardCount}, transactions)
+		if !slices.Equal(expected, results) {
+			t.Errorf("[%d shards] Expected %v, got %v", shardCount, expected, results)
+		}
+	}
+}
+
+func TestReconService_ReconcileSpilled(t *testing.T) {
+	transactions := reconcileTestTransactions()
+
+	// shuffle dates so the sort has work to do
+	slices.Reverse(transactions)
+
+	expected := reconcileResults(t, NewReconServiceOpts{}, transactions)
+	for _, runSize := range []int{1, 7, 1000} {
+		dir := t.TempDir()
+		results := reconcileResults(t, NewReconServiceOpts{SpillDir: dir, SpillRunSize: runSize}, transactions)
+		if !slices.Equal(expected, results) {
+			t.Errorf("[run size %d] Expected %v, got %v", runSize, expected, results)
+		}
+
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 0 {
+			t.Errorf("[run size %d] Expected spill files removed, got %d entries", runSize, len(entries))
+		}
+	}
+}
+
+func TestReconService_ReconcileStorage(t *testing.T) {
+	transactions := reconcileTestTransactions()
+	expected := reconcileResults(t, NewReconServiceOpts{}, transactions)
+
+	// the same file is reused with another shard count, rows of the earlier run are cleared
+	path := filepath.Join(t.TempDir(), "recon.db")
+	for _, shardCount := range []int{1, 3, 2} {
+		results := reconcileResults(t, NewReconServiceOpts{StoragePath: path, ShardCount: shardCount}, transactions)
+		if !slices.Equal(expected, results) {
+			t.Errorf("[%d shards] Expected %v, got %v", shardCount, expected, results)
+		}
+
+		// unmatched transactions survive the run
+		store, err := storage.OpenBoltStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		unmatched := 0
+		for _, name := range []string{"internal", "external"} {
+			table, err := store.Table(name, storage.GobCodec[model.Transaction]{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			table.Each(func(obj storage.IHashable) bool {
+				unmatched += 1
+				return true
+			})
+		}
+		store.Close()
+
+		// 14 days with two unmatched internal and one unmatched external
+		if unmatched != 42 {
+			t.Errorf("[%d shards] Expected 42 stored unmatched transactions, got %d", shardCount, unmatched)
+		}
+	}
+}
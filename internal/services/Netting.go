@@ -0,0 +1,171 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Reversal netting, pairs a transaction with the entry reversing it within the
+// same source before matching, so a bank debit followed by its reversal credit
+// or an Amartha entry and its cancellation are not reported as two breaks.
+//
+// A reversal names the entry it reverses in ReversalOf, by id or reference.
+// Pairs share source and amount, have opposite types and are dated at most
+// NettingConfig.WindowDays apart. Both legs are emitted as self-cancelled and
+// never reach the matcher.
+//
+// A reversal can arrive anywhere in the input, so the stage holds every
+// transaction until the input ends, NewReconService rejects it with SpillDir
+// or StoragePath, which are chosen to bound memory.
+// Error records pass straight through.
+
+type NettingConfig struct {
+	WindowDays int // days between a transaction and its reversal, 0 nets same day reversals only
+}
+
+var oppositeTypes = map[string]string{
+	"CREDIT": "DEBIT",
+	"DEBIT":  "CREDIT",
+}
+
+// netReversals emits the reversal pairs of transactionChan as self-cancelled and forwards the rest in input order
+func (r *ReconService) netReversals(
+	transactionChan <-chan model.Transaction,
+	config NettingConfig,
+	emit func(ReconTransaction) bool,
+) <-chan model.Transaction {
+	outChan := make(chan model.Transaction, r.bufferSize)
+
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+
+		held := []model.Transaction{}
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, nil)
+			if !ok {
+				break
+			}
+
+			if transaction.ParseError != nil {
+				if !pipeline.Send(r.Ctx, outChan, transaction) {
+					return context.Cause(r.Ctx)
+				}
+				continue
+			}
+
+			held = append(held, transaction)
+		}
+
+		if r.Ctx.Err() != nil {
+			return context.Cause(r.Ctx)
+		}
+
+		counterparts := pairReversals(held, config.WindowDays)
+		for i, transaction := range held {
+			j, ok := counterparts[i]
+			if !ok {
+				if !pipeline.Send(r.Ctx, outChan, transaction) {
+					return context.Cause(r.Ctx)
+				}
+				continue
+			}
+
+			counterpart := held[j]
+			if !emit(selfCancelledReconTransaction(transaction, counterpart, reverses(counterpart, transaction))) {
+				return context.Cause(r.Ctx)
+			}
+		}
+
+		return nil
+	})
+
+	return outChan
+}
+
+// pairReversals returns the index of the counterpart of every paired transaction. Reversals are
+// paired by date, each with the earliest unpaired entry it names in the window, the input order breaks ties.
+func pairReversals(transactions []model.Transaction, windowDays int) map[int]int {
+	window := int64(windowDays) * (24 * time.Hour).Milliseconds()
+
+	// entries by source, id or reference and amount
+	entries := make(map[string][]int)
+	reversals := []int{}
+	for i, t := range transactions {
+		if _, ok := oppositeTypes[t.Type]; !ok {
+			continue
+		}
+
+		key := reversalKey(t.Source, t.Id, t.Amount)
+		entries[key] = append(entries[key], i)
+		if t.Reference != "" && t.Reference != t.Id {
+			key := reversalKey(t.Source, t.Reference, t.Amount)
+			entries[key] = append(entries[key], i)
+		}
+		if t.ReversalOf != "" {
+			reversals = append(reversals, i)
+		}
+	}
+
+	slices.SortStableFunc(reversals, func(a, b int) int {
+		return cmp.Compare(transactions[a].DateEpoch, transactions[b].DateEpoch)
+	})
+
+	counterparts := make(map[int]int)
+	for _, i := range reversals {
+		if _, ok := counterparts[i]; ok {
+			continue
+		}
+
+		reversal := transactions[i]
+		original := -1
+		for _, j := range entries[reversalKey(reversal.Source, reversal.ReversalOf, reversal.Amount)] {
+			if _, ok := counterparts[j]; ok || j == i || transactions[j].Type != oppositeTypes[reversal.Type] {
+				continue
+			}
+
+			distance := max(transactions[j].DateEpoch-reversal.DateEpoch, reversal.DateEpoch-transactions[j].DateEpoch)
+			if distance > window {
+				continue
+			}
+
+			if original == -1 || transactions[j].DateEpoch < transactions[original].DateEpoch {
+				original = j
+			}
+		}
+
+		if original != -1 {
+			counterparts[i], counterparts[original] = original, i
+		}
+	}
+
+	return counterparts
+}
+
+func reversalKey(source string, reference string, amount float64) string {
+	return fmt.Sprintf("%s|%s|%.2f", source, reference, amount)
+}
+
+// reverses reports whether reversal names original as the entry it reverses
+func reverses(reversal model.Transaction, original model.Transaction) bool {
+	return reversal.ReversalOf != "" && (reversal.ReversalOf == original.Id || reversal.ReversalOf == original.Reference)
+}
+
+func selfCancelledReconTransaction(transaction model.Transaction, counterpart model.Transaction, isOriginal bool) ReconTransaction {
+	remark := fmt.Sprintf("Reversal of %s from %s", counterpart.Id, counterpart.Date)
+	if isOriginal {
+		remark = fmt.Sprintf("Reversed by %s on %s", counterpart.Id, counterpart.Date)
+	}
+
+	return ReconTransaction{
+		Transaction:      transaction,
+		OtherTransaction: counterpart,
+		IsSelfCancelled:  true,
+		Remark:           remark,
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func nettingTransaction(source string, id string, reversalOf string, txnType string, amount float64, date string) model.Transaction {
+	t, _ := time.Parse(time.DateOnly, date)
+	return model.Transaction{Source: source, Id: id, ReversalOf: reversalOf, Type: txnType, Amount: amount, Date: date, DateEpoch: t.UnixMilli()}
+}
+
+func TestReconService_NetReversals(t *testing.T) {
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:     context.Background(),
+		Netting: &NettingConfig{WindowDays: 3},
+	})
+	newService.internalSource = "amartha"
+
+	bankEntry := nettingTransaction("mt940", "cust_1", "", "DEBIT", 15, "2025-01-01")
+	bankEntry.Reference = "bank_1"
+
+	transactions := []model.Transaction{
+		// reversal arriving before the debit it reverses
+		nettingTransaction("bca", "bca_rev", "bca_1", "CREDIT", 50, "2025-01-03"),
+		nettingTransaction("bca", "bca_1", "", "DEBIT", 50, "2025-01-01"),
+		// amartha entry and its cancellation
+		nettingTransaction("amartha", "loan_1", "", "DEBIT", 20, "2025-01-02"),
+		nettingTransaction("amartha", "loan_1_cancel", "loan_1", "CREDIT", 20, "2025-01-02"),
+		// reversal naming the bank reference of the entry
+		bankEntry,
+		nettingTransaction("mt940", "mt940_rev", "bank_1", "CREDIT", 15, "2025-01-02"),
+		// outside the window
+		nettingTransaction("bca", "bca_2", "", "DEBIT", 10, "2025-01-01"),
+		nettingTransaction("bca", "bca_2_rev", "bca_2", "CREDIT", 10, "2025-01-05"),
+		// the entry is in another source
+		nettingTransaction("dbs", "dbs_1", "", "DEBIT", 30, "2025-01-01"),
+		nettingTransaction("amartha", "amartha_3", "dbs_1", "CREDIT", 30, "2025-01-01"),
+		// same type is not a reversal
+		nettingTransaction("amartha", "dup", "", "DEBIT", 5, "2025-01-01"),
+		nettingTransaction("amartha", "dup_2", "dup", "DEBIT", 5, "2025-01-01"),
+		// opposite entries of the same amount without a reversal reference
+		nettingTransaction("bca", "bca_3", "", "DEBIT", 7, "2025-01-04"),
+		nettingTransaction("bca", "bca_4", "", "CREDIT", 7, "2025-01-04"),
+		{Source: "dbs", Id: "dbs_error", ParseError: errors.New("invalid amount")},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selfCancelled := map[string]ReconTransaction{}
+	others := map[string]ReconTransaction{}
+	for rt := range reconChan {
+		if rt.IsSelfCancelled {
+			selfCancelled[rt.Id+"|"+rt.Type] = rt
+			continue
+		}
+		others[rt.Id] = rt
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"bca_1|DEBIT":          "Reversed by bca_rev on 2025-01-03",
+		"bca_rev|CREDIT":       "Reversal of bca_1 from 2025-01-01",
+		"loan_1|DEBIT":         "Reversed by loan_1_cancel on 2025-01-02",
+		"loan_1_cancel|CREDIT": "Reversal of loan_1 from 2025-01-02",
+		"cust_1|DEBIT":         "Reversed by mt940_rev on 2025-01-02",
+		"mt940_rev|CREDIT":     "Reversal of cust_1 from 2025-01-01",
+	}
+	if len(selfCancelled) != len(expected) {
+		t.Errorf("Expected %d self-cancelled legs, got %+v", len(expected), selfCancelled)
+	}
+	for key, remark := range expected {
+		if rt := selfCancelled[key]; rt.Remark != remark || rt.IsUnmatched() {
+			t.Errorf("Expected %s self-cancelled with %q, got %+v", key, remark, rt)
+		}
+	}
+
+	for _, id := range []string{"bca_2", "bca_2_rev", "dbs_1", "amartha_3", "dup", "dup_2", "bca_3", "bca_4"} {
+		if rt, ok := others[id]; !ok || !rt.IsUnmatched() {
+			t.Errorf("Expected %s left unmatched, got %+v", id, rt)
+		}
+	}
+	if rt := others["dbs_error"]; !rt.IsError {
+		t.Errorf("Expected the error record passed through, got %+v", rt)
+	}
+
+	if _, ok := newService.FilterMismatched(selfCancelled["bca_1|DEBIT"]); ok {
+		t.Errorf("Expected self-cancelled legs left out of the mismatch report")
+	}
+}
+
+func TestReconService_NettingWithSpillDir(t *testing.T) {
+	for _, opts := range []NewReconServiceOpts{
+		{Netting: &NettingConfig{WindowDays: 3}, SpillDir: t.TempDir()},
+		{Netting: &NettingConfig{WindowDays: 3}, StoragePath: filepath.Join(t.TempDir(), "recon.db")},
+	} {
+		if _, err := NewReconService(opts); err == nil {
+			t.Errorf("Expected error for netting with %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Open items ledger, a JSON Lines file of the transactions left unmatched by
+// the previous run.
+//
+// Flow:
+// (1) Reconcile loads the ledger, a new transaction with the type and id of an
+//     open item of the other side matches it whatever its date
+//     the residual of a partial match is also matched by a related transaction
+//     of the other side with its type and amount, related as for partial
+//     matching, and smaller related transactions are held until the input
+//     ends to cover it in parts
+// (2) open items left once the input ends are reconciled like transactions
+//     arriving last, so they can still match by amount or date
+// (3) PassThroughOpenItems writes every unmatched transaction of the run to a
+//     temporary file, Wait renames it over the ledger once every stage
+//     succeeded, downstream writers included
+
+type openItemRecord struct {
+	Source         string  `json:"source"`
+	Id             string  `json:"id"`
+	Type           string  `json:"type"`
+	Amount         float64 `json:"amount"`
+	Date           string  `json:"date"`
+	DateEpoch      int64   `json:"date_epoch"`
+	Reference      string  `json:"reference,omitempty"`
+	Narrative      string  `json:"narrative,omitempty"`
+	VirtualAccount string  `json:"virtual_account,omitempty"`
+	ReversalOf     string  `json:"reversal_of,omitempty"`
+	SourceFile     string  `json:"source_file,omitempty"`
+	SourceRow      int     `json:"source_row,omitempty"`
+	Residual       bool    `json:"residual,omitempty"`
+}
+
+func newOpenItemRecord(t model.Transaction) openItemRecord {
+	return openItemRecord{
+		Source:         t.Source,
+		Id:             t.Id,
+		Type:           t.Type,
+		Amount:         t.Amount,
+		Date:           t.Date,
+		DateEpoch:      t.DateEpoch,
+		Reference:      t.Reference,
+		Narrative:      t.Narrative,
+		VirtualAccount: t.VirtualAccount,
+		ReversalOf:     t.ReversalOf,
+		SourceFile:     t.SourceFile,
+		SourceRow:      t.SourceRow,
+		Residual:       t.CarriedResidual,
+	}
+}
+
+func (o openItemRecord) transaction() model.Transaction {
+	return model.Transaction{
+		Source:          o.Source,
+		Id:              o.Id,
+		Type:            o.Type,
+		Amount:          o.Amount,
+		Date:            o.Date,
+		DateEpoch:       o.DateEpoch,
+		Reference:       o.Reference,
+		Narrative:       o.Narrative,
+		VirtualAccount:  o.VirtualAccount,
+		ReversalOf:      o.ReversalOf,
+		SourceFile:      o.SourceFile,
+		SourceRow:       o.SourceRow,
+		CarriedForward:  true,
+		CarriedResidual: o.Residual,
+	}
+}
+
+// LoadOpenItems reads the ledger at path, a missing ledger has no open items
+func LoadOpenItems(path string) ([]model.Transaction, error) {
+	items, _, err := loadOpenItems(path)
+	return items, err
+}
+
+// loadOpenItems also returns the checksum of the ledger as read, nil when it is missing.
+// Every open item is held in memory anyway, so the ledger is read at once and hashed from the same bytes.
+func loadOpenItems(path string) ([]model.Transaction, *ingester.FileChecksum, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open open items ledger: %w", err)
+	}
+
+	items := []model.Transaction{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var record openItemRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, nil, fmt.Errorf("failed to read open items ledger: %w", err)
+		}
+		items = append(items, record.transaction())
+	}
+
+	sum := sha256.Sum256(content)
+	return items, &ingester.FileChecksum{Path: path, Size: int64(len(content)), Sha256: hex.EncodeToString(sum[:])}, nil
+}
+
+// openItems indexes carried forward transactions by side, type and id, and residuals also by side and type
+type openItems struct {
+	internalSource string
+	items          []model.Transaction
+	index          map[string]int   // first item of a key, matched ones are dropped lazily
+	residuals      map[string][]int // residual items of a side and type in ledger order
+	held           []model.Transaction
+	matched        map[int]bool
+}
+
+func newOpenItems(internalSource string, items []model.Transaction) *openItems {
+	o := &openItems{
+		internalSource: internalSource,
+		items:          items,
+		index:          make(map[string]int, len(items)),
+		residuals:      make(map[string][]int),
+		matched:        make(map[int]bool),
+	}
+
+	for i, item := range items {
+		isInternal := item.Source == internalSource
+		key := o.key(isInternal, item)
+		if _, ok := o.index[key]; !ok {
+			o.index[key] = i
+		}
+
+		if item.CarriedResidual {
+			key := o.residualKey(isInternal, item)
+			o.residuals[key] = append(o.residuals[key], i)
+		}
+	}
+
+	return o
+}
+
+func openItemSide(isInternal bool) string {
+	if isInternal {
+		return "internal"
+	}
+	return "external"
+}
+
+func (o *openItems) key(isInternal bool, t model.Transaction) string {
+	return openItemSide(isInternal) + "|" + t.Type + "|" + t.Id
+}
+
+func (o *openItems) residualKey(isInternal bool, t model.Transaction) string {
+	return openItemSide(isInternal) + "|" + t.Type
+}
+
+// match takes the open item of the other side with the type and id of transaction,
+// or else the first residual of the other side with its type and amount related to it
+func (o *openItems) match(transaction model.Transaction) (ReconTransaction, bool) {
+	isInternal := transaction.Source != o.internalSource
+
+	key := o.key(isInternal, transaction)
+	if i, ok := o.index[key]; ok {
+		delete(o.index, key)
+		if !o.matched[i] {
+			o.matched[i] = true
+
+			item := o.items[i]
+			return ReconTransaction{
+				Transaction:      transaction,
+				OtherTransaction: item,
+				IsMatched:        true,
+				MatchRule:        MatchRuleOpenItem,
+				Remark:           fmt.Sprintf("Matched open item of %s from %s", item.Source, item.Date),
+			}, true
+		}
+	}
+
+	for _, i := range o.residuals[o.residualKey(isInternal, transaction)] {
+		item := o.items[i]
+		if o.matched[i] || toCents(item.Amount) != toCents(transaction.Amount) || !isPartialCandidate(item, transaction) {
+			continue
+		}
+		o.matched[i] = true
+
+		return ReconTransaction{
+			Transaction:      transaction,
+			OtherTransaction: item,
+			IsMatched:        true,
+			MatchRule:        MatchRuleOpenItem,
+			Remark:           fmt.Sprintf("Covers the residual of open item %s of %s from %s", item.Id, item.Source, item.Date),
+		}, true
+	}
+
+	return ReconTransaction{}, false
+}
+
+// hold keeps transaction aside when it may cover part of a residual, coverResiduals decides once the input ends
+func (o *openItems) hold(transaction model.Transaction) bool {
+	isInternal := transaction.Source != o.internalSource
+
+	for _, i := range o.residuals[o.residualKey(isInternal, transaction)] {
+		item := o.items[i]
+		if !o.matched[i] && transaction.Amount < item.Amount && isPartialCandidate(item, transaction) {
+			o.held = append(o.held, transaction)
+			return true
+		}
+	}
+	return false
+}
+
+// coverResiduals covers every residual in ledger order with the held transactions related to it,
+// emits the PARTIAL pairs and returns the held transactions left
+func (o *openItems) coverResiduals(emit func(ReconTransaction) bool) ([]model.Transaction, bool) {
+	// held in arrival order, sorted so a run over the same input always pairs the same way
+	slices.SortStableFunc(o.held, func(a, b model.Transaction) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.Date, b.Date), cmp.Compare(a.Id, b.Id))
+	})
+	taken := make([]bool, len(o.held))
+
+	for i, item := range o.items {
+		if o.matched[i] || !item.CarriedResidual {
+			continue
+		}
+
+		candidates := []int{}
+		amounts := []float64{}
+		for j, transaction := range o.held {
+			isInternal := transaction.Source != o.internalSource
+			if !taken[j] && o.residualKey(isInternal, transaction) == o.residualKey(item.Source == o.internalSource, item) &&
+				transaction.Amount < item.Amount && isPartialCandidate(item, transaction) {
+				candidates = append(candidates, j)
+				amounts = append(amounts, transaction.Amount)
+			}
+		}
+
+		covering := bestPartialCover(amounts, item.Amount)
+		residual := item.Amount
+		for _, k := range covering {
+			j := candidates[k]
+			taken[j] = true
+			residual = roundCents(residual - o.held[j].Amount)
+
+			if !emit(ReconTransaction{
+				Transaction:      o.held[j],
+				OtherTransaction: item,
+				IsMatched:        true,
+				MatchRule:        MatchRulePartial,
+				Residual:         residual,
+				Remark:           fmt.Sprintf("Covers part of the residual of open item %s of %s from %s, %.2f of %.2f left", item.Id, item.Source, item.Date, residual, item.Amount),
+			}) {
+				return nil, false
+			}
+		}
+
+		if residual < discrepancyTolerance {
+			o.matched[i] = true
+		} else {
+			o.items[i].Amount = residual
+		}
+	}
+
+	left := []model.Transaction{}
+	for j, transaction := range o.held {
+		if !taken[j] {
+			left = append(left, transaction)
+		}
+	}
+	o.held = nil
+	return left, true
+}
+
+// remaining returns the unmatched open items in ledger order
+func (o *openItems) remaining() []model.Transaction {
+	remaining := make([]model.Transaction, 0, len(o.items)-len(o.matched))
+	for i, item := range o.items {
+		if !o.matched[i] {
+			remaining = append(remaining, item)
+		}
+	}
+	return remaining
+}
+
+// carryForward matches transactionChan against the open items, then forwards the open items left
+func (r *ReconService) carryForward(
+	transactionChan <-chan model.Transaction,
+	items *openItems,
+	emit func(ReconTransaction) bool,
+) <-chan model.Transaction {
+	outChan := make(chan model.Transaction, r.bufferSize)
+
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, nil)
+			if !ok {
+				break
+			}
+
+			if transaction.ParseError == nil {
+				if reconTransaction, ok := items.match(transaction); ok {
+					if !emit(reconTransaction) {
+						return context.Cause(r.Ctx)
+					}
+					continue
+				}
+				if items.hold(transaction) {
+					continue
+				}
+			}
+
+			if !pipeline.Send(r.Ctx, outChan, transaction) {
+				return context.Cause(r.Ctx)
+			}
+		}
+
+		if r.Ctx.Err() != nil {
+			return context.Cause(r.Ctx)
+		}
+
+		left, ok := items.coverResiduals(emit)
+		if !ok {
+			return context.Cause(r.Ctx)
+		}
+
+		// held transactions not covering a residual are reconciled like any other
+		for _, transaction := range append(left, items.remaining()...) {
+			if !pipeline.Send(r.Ctx, outChan, transaction) {
+				return context.Cause(r.Ctx)
+			}
+		}
+
+		return nil
+	})
+
+	return outChan
+}
+
+// PassThroughOpenItems writes the unmatched transactions of the run as the next open items ledger,
+// place it right after Reconcile so every unmatched transaction is seen
+func (r *ReconService) PassThroughOpenItems(reconTransactionChan <-chan ReconTransaction) <-chan ReconTransaction {
+	if r.openItemsPath == "" {
+		return reconTransactionChan
+	}
+
+	outChan := make(chan ReconTransaction, r.bufferSize)
+	metrics := r.metrics.Stage("open_items")
+
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+
+		file, err := os.CreateTemp(filepath.Dir(r.openItemsPath), ".open-items-*.jsonl")
+		if err != nil {
+			return fmt.Errorf("failed to write open items ledger: %w", err)
+		}
+		// removed by commitOpenItems once handed over
+		defer file.Close()
+		written := false
+		defer func() {
+			if !written {
+				os.Remove(file.Name())
+			}
+		}()
+
+		writer := bufio.NewWriter(file)
+		encoder := json.NewEncoder(writer)
+
+		for {
+			t, ok := pipeline.Receive(r.Ctx, reconTransactionChan, metrics)
+			if !ok {
+				break
+			}
+
+			if t.IsUnmatched() {
+				// a partially matched transaction is carried with its residual left to match
+				item := t.Transaction
+				item.Amount = t.OpenAmount()
+				item.CarriedResidual = item.CarriedResidual || t.MatchRule == MatchRulePartial
+				if err := encoder.Encode(newOpenItemRecord(item)); err != nil {
+					return fmt.Errorf("failed to write open items ledger: %w", err)
+				}
+			}
+
+			if !pipeline.SendMeasured(r.Ctx, outChan, t, metrics) {
+				return context.Cause(r.Ctx)
+			}
+		}
+
+		// keep the previous ledger when the run failed
+		if r.Ctx.Err() != nil {
+			return context.Cause(r.Ctx)
+		}
+
+		if err := writer.Flush(); err != nil {
+			return fmt.Errorf("failed to write open items ledger: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write open items ledger: %w", err)
+		}
+
+		written = true
+		r.openItemsTemp = file.Name()
+		return nil
+	})
+
+	return outChan
+}
+
+// commitOpenItems replaces the ledger with the one written by PassThroughOpenItems
+// when the run succeeded, and drops it otherwise so the previous ledger is kept
+func (r *ReconService) commitOpenItems(runErr error) error {
+	if r.openItemsTemp == "" {
+		return runErr
+	}
+
+	temp := r.openItemsTemp
+	r.openItemsTemp = ""
+	if runErr != nil {
+		os.Remove(temp)
+		return runErr
+	}
+
+	// CreateTemp leaves the file readable by its owner only
+	if err := os.Chmod(temp, 0644); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to write open items ledger: %w", err)
+	}
+	if err := os.Rename(temp, r.openItemsPath); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to write open items ledger: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func runWithOpenItems(t *testing.T, path string, transactions []model.Transaction) []ReconTransaction {
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:           context.Background(),
+		OpenItemsPath: path,
+	})
+	newService.internalSource = "internal"
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []ReconTransaction{}
+	for rt := range newService.PassThroughOpenItems(reconChan) {
+		results = append(results, rt)
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	return results
+}
+
+func TestReconService_OpenItemsCarryForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "open_items.jsonl")
+
+	// day one, the bank credit has not arrived yet
+	runWithOpenItems(t, path, []model.Transaction{
+		{Source: "internal", Id: "late", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "internal", Id: "same_day", Type: "DEBIT", Amount: 20, Date: "2025-01-01"},
+	})
+
+	items, err := LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || !items[0].CarriedForward {
+		t.Fatalf("Expected 2 carried forward open items, got %+v", items)
+	}
+
+	// day two, the credit arrives with the next day date and a late file brings the debit of day one
+	results := runWithOpenItems(t, path, []model.Transaction{
+		{Source: "bca", Id: "late", Type: "CREDIT", Amount: 100, Date: "2025-01-02"},
+		{Source: "bca", Id: "bca_ref", Type: "DEBIT", Amount: 20, Date: "2025-01-01"},
+		{Source: "bca", Id: "new", Type: "DEBIT", Amount: 5, Date: "2025-01-02"},
+	})
+
+	matched := map[string]ReconTransaction{}
+	for _, rt := range results {
+		if rt.IsMatched {
+			matched[rt.Id] = rt
+			matched[rt.OtherTransaction.Id] = rt
+		}
+	}
+
+	late, ok := matched["late"]
+	if !ok || late.OtherTransaction.Date != "2025-01-01" || late.Remark != "Matched open item of internal from 2025-01-01" {
+		t.Errorf("Expected late credit matched against the open item, got %+v", late)
+	}
+
+	if _, ok := matched["bca_ref"]; !ok {
+		t.Errorf("Expected the late debit matched by amount, got %+v", results)
+	}
+
+	items, err = LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Id != "new" || items[0].Source != "bca" {
+		t.Errorf("Expected only the new debit left open, got %+v", items)
+	}
+}
+
+func TestReconService_OpenItemsMissingLedger(t *testing.T) {
+	items, err := LoadOpenItems(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil || len(items) != 0 {
+		t.Errorf("Expected no open items, got %v %v", items, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.jsonl")
+	os.WriteFile(path, []byte("{not json\n"), 0644)
+
+	if _, err := LoadOpenItems(path); err == nil {
+		t.Errorf("Expected error for a broken ledger")
+	}
+}
+
+func TestReconService_OpenItemsRerun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "open_items.jsonl")
+	transactions := []model.Transaction{
+		{Source: "internal", Id: "open", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "bca", Id: "bca_open", Type: "DEBIT", Amount: 20, Date: "2025-01-01"},
+	}
+
+	runWithOpenItems(t, path, transactions)
+	// the same files read again with the ledger of the first run
+	results := runWithOpenItems(t, path, transactions)
+
+	if len(results) != len(transactions) {
+		t.Errorf("Expected every transaction reported once, got %+v", results)
+	}
+	for _, rt := range results {
+		if rt.IsError || !rt.IsUnmatched() || rt.CarriedForward {
+			t.Errorf("Expected the row read left unmatched instead of its carried copy, got %+v", rt)
+		}
+	}
+
+	items, err := LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(transactions) {
+		t.Errorf("Expected %d open items, got %+v", len(transactions), items)
+	}
+}
+
+func TestReconService_OpenItemsKeptOnFailedRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "open_items.jsonl")
+	runWithOpenItems(t, path, []model.Transaction{
+		{Source: "internal", Id: "open", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+	})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected the ledger at mode 0644, got %v", info.Mode().Perm())
+	}
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:           context.Background(),
+		OpenItemsPath: path,
+	})
+	newService.internalSource = "internal"
+
+	inChan := make(chan model.Transaction, 1)
+	inChan <- model.Transaction{Source: "bca", Id: "new", Type: "DEBIT", Amount: 5, Date: "2025-01-02"}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range newService.PassThroughOpenItems(reconChan) {
+	}
+
+	// a writer failing once the stream ended
+	newService.group.Fail(errors.New("disk full"))
+	if err := newService.Wait(); err == nil {
+		t.Fatal("Expected the writer error")
+	}
+
+	items, err := LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Id != "open" {
+		t.Errorf("Expected the previous ledger kept, got %+v", items)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected the temporary ledger removed, got %v", entries)
+	}
+}
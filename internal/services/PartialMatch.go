@@ -0,0 +1,242 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/storage"
+)
+
+// Partial matching, covers one internal transaction with several external
+// ones, for an installment repaid in two transfers or a payout split by the
+// bank.
+//
+// It runs once the input ends, after the id and amount rules and before the
+// date rule. The internal transactions still unmatched are taken by date,
+// type and id, so a run over the same input always pairs the same way. Each
+// takes the subset of the remaining external transactions of its date and
+// type that covers most of its amount, an exact cover when there is one.
+// Only externals related to the internal one are candidates: the same id, a
+// reference naming it, the same reference or the same virtual account.
+//
+// A bank split often carries the internal id. The id rule leaves an external
+// smaller than the internal of its id to this pass, and the matcher holds the
+// further legs with the same date, type and id as split legs instead of
+// reporting them as duplicates, see isSplitLeg.
+//
+// Each external taken is a PARTIAL pair carrying the residual left after it.
+// An internal transaction left with a residual is reported as a break whose
+// open amount is the residual. Carried forward, it is matched by a related
+// transaction of its amount or covered in parts by smaller ones, see openItems.
+
+// partialCoverBudget bounds the subsets tried for one internal transaction, the best cover found so far is kept
+const partialCoverBudget = 100000
+
+// partialLeg is a remaining external transaction, from the external table or held as a split leg
+type partialLeg struct {
+	model.Transaction
+	held  bool
+	taken bool
+}
+
+// partialMatchGroups returns the remaining external transactions by date and type, largest amount first
+func (m *matcher) partialMatchGroups() map[string][]*partialLeg {
+	groups := make(map[string][]*partialLeg)
+	add := func(transaction model.Transaction, held bool) {
+		key := strings.Join(transaction.GetKeySearchByDate(), "|")
+		groups[key] = append(groups[key], &partialLeg{Transaction: transaction, held: held})
+	}
+
+	m.externalTable.Each(func(obj storage.IHashable) bool {
+		add(obj.(model.Transaction), false)
+		return true
+	})
+	for _, legs := range m.splitLegs {
+		for _, leg := range legs {
+			add(leg, true)
+		}
+	}
+
+	for _, group := range groups {
+		slices.SortStableFunc(group, func(a, b *partialLeg) int {
+			return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.Id, b.Id), cmp.Compare(a.SourceRow, b.SourceRow))
+		})
+	}
+	return groups
+}
+
+// partialMatchInternals returns the remaining internal transactions by date, type and id
+func (m *matcher) partialMatchInternals() []model.Transaction {
+	internals := []model.Transaction{}
+	m.internalTable.Each(func(obj storage.IHashable) bool {
+		internals = append(internals, obj.(model.Transaction))
+		return true
+	})
+
+	slices.SortFunc(internals, func(a, b model.Transaction) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.Type, b.Type), cmp.Compare(a.Id, b.Id))
+	})
+	return internals
+}
+
+// isPartialCandidate reports whether external may cover part of internal
+func isPartialCandidate(internal model.Transaction, external model.Transaction) bool {
+	switch {
+	case external.Id == internal.Id, external.Reference == internal.Id:
+		return true
+	case internal.Reference != "" && external.Reference == internal.Reference:
+		return true
+	case internal.VirtualAccount != "" && external.VirtualAccount == internal.VirtualAccount:
+		return true
+	}
+	return false
+}
+
+// matchPartial emits the partial pairs and residual breaks of the remaining internal transactions,
+// returns false when emit was cancelled
+func (m *matcher) matchPartial(emit func(ReconTransaction) bool) bool {
+	groups := m.partialMatchGroups()
+
+	for _, internal := range m.partialMatchInternals() {
+		candidates := []*partialLeg{}
+		for _, leg := range groups[strings.Join(internal.GetKeySearchByDate(), "|")] {
+			if !leg.taken && isPartialCandidate(internal, leg.Transaction) && leg.Amount < internal.Amount+discrepancyTolerance {
+				candidates = append(candidates, leg)
+			}
+		}
+
+		amounts := make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			amounts[i] = candidate.Amount
+		}
+
+		covering := bestPartialCover(amounts, internal.Amount)
+		if len(covering) == 0 {
+			continue
+		}
+
+		m.internalTable.Remove(internal)
+
+		residual := internal.Amount
+		for _, i := range covering {
+			leg := candidates[i]
+			leg.taken = true
+			if !leg.held {
+				m.externalTable.Remove(leg.Transaction)
+			}
+			residual = roundCents(residual - leg.Amount)
+
+			if !emit(ReconTransaction{
+				Transaction:      leg.Transaction,
+				OtherTransaction: internal,
+				IsMatched:        true,
+				MatchRule:        MatchRulePartial,
+				Residual:         residual,
+				Remark:           fmt.Sprintf("Partially covers %s, %.2f of %.2f left", internal.Id, residual, internal.Amount),
+			}) {
+				return false
+			}
+		}
+
+		if residual < discrepancyTolerance {
+			continue
+		}
+
+		if !emit(ReconTransaction{
+			Transaction: internal,
+			MatchRule:   MatchRulePartial,
+			Residual:    residual,
+			Remark:      fmt.Sprintf("Partially matched by %d external transactions, %.2f of %.2f left", len(covering), residual, internal.Amount),
+		}) {
+			return false
+		}
+	}
+
+	// split legs left over go on to the date rule and the unmatched report
+	clear(m.splitLegs)
+	for _, group := range groups {
+		for _, leg := range group {
+			if leg.held && !leg.taken {
+				hashKey := leg.GetHashById()
+				m.splitLegs[hashKey] = append(m.splitLegs[hashKey], leg.Transaction)
+			}
+		}
+	}
+
+	return true
+}
+
+// bestPartialCover returns the indexes of amounts, sorted largest first, adding up to most of target without
+// exceeding it. The search backtracks in cents and stops at an exact cover or once its budget is spent.
+func bestPartialCover(amounts []float64, target float64) []int {
+	targetCents := toCents(target)
+	cents := make([]int64, len(amounts))
+	for i, amount := range amounts {
+		cents[i] = toCents(amount)
+	}
+
+	// visiting the largest first finds a close cover early and prunes more
+	order := make([]int, len(cents))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(cents[b], cents[a]) })
+
+	// remaining[i] is the most the amounts from order[i] on can still add
+	remaining := make([]int64, len(order)+1)
+	for i := len(order) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + cents[order[i]]
+	}
+
+	var best, chosen []int
+	var bestSum int64
+	budget := partialCoverBudget
+
+	var search func(i int, sum int64) bool
+	search = func(i int, sum int64) bool {
+		if sum > bestSum {
+			best, bestSum = slices.Clone(chosen), sum
+		}
+		if bestSum == targetCents {
+			return true
+		}
+
+		budget -= 1
+		if i == len(order) || budget <= 0 || sum+remaining[i] <= bestSum {
+			return false
+		}
+
+		if next := cents[order[i]]; sum+next <= targetCents {
+			chosen = append(chosen, order[i])
+			if search(i+1, sum+next) {
+				return true
+			}
+			chosen = chosen[:len(chosen)-1]
+		}
+		return search(i+1, sum)
+	}
+	search(0, 0)
+
+	return best
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// pairDifference is the internal minus the external amount of a matched pair,
+// the external of a partial pair covers exactly its share of the internal
+func pairDifference(rt ReconTransaction, internal model.Transaction, external model.Transaction) float64 {
+	if rt.MatchRule == MatchRulePartial {
+		return 0
+	}
+	return internal.Amount - external.Amount
+}
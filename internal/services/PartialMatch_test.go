@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func TestReconService_PartialMatching(t *testing.T) {
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:             context.Background(),
+		PartialMatching: true,
+	})
+	newService.internalSource = "amartha"
+
+	transactions := []model.Transaction{
+		// installment repaid in two transfers naming it
+		{Source: "amartha", Id: "installment", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "bca", Id: "transfer_1", Reference: "installment", Type: "CREDIT", Amount: 60, Date: "2025-01-01"},
+		{Source: "bca", Id: "transfer_2", Reference: "installment", Type: "CREDIT", Amount: 40, Date: "2025-01-01"},
+		// payout only partly paid out by the bank
+		{Source: "amartha", Id: "payout", Type: "DEBIT", Amount: 50, Date: "2025-01-01"},
+		{Source: "bca", Id: "split_1", Reference: "payout", Type: "DEBIT", Amount: 30, Date: "2025-01-01"},
+		{Source: "bca", Id: "too_large", Reference: "payout", Type: "DEBIT", Amount: 80, Date: "2025-01-01"},
+		// exact amounts still match first
+		{Source: "amartha", Id: "exact", Type: "CREDIT", Amount: 25, Date: "2025-01-02"},
+		{Source: "bca", Id: "bca_exact", Type: "CREDIT", Amount: 25, Date: "2025-01-02"},
+		// an exact cover the largest first would miss
+		{Source: "amartha", Id: "repayment", VirtualAccount: "8808001", Type: "CREDIT", Amount: 100, Date: "2025-01-03"},
+		{Source: "bca", Id: "va_70", VirtualAccount: "8808001", Type: "CREDIT", Amount: 70, Date: "2025-01-03"},
+		{Source: "bca", Id: "va_50_a", VirtualAccount: "8808001", Type: "CREDIT", Amount: 50, Date: "2025-01-03"},
+		{Source: "bca", Id: "va_50_b", VirtualAccount: "8808001", Type: "CREDIT", Amount: 50, Date: "2025-01-03"},
+		// same day debits adding up to the fee without naming it
+		{Source: "amartha", Id: "fee", Reference: "fee_ref", Type: "DEBIT", Amount: 40, Date: "2025-01-04"},
+		{Source: "bca", Id: "unrelated_1", Type: "DEBIT", Amount: 30, Date: "2025-01-04"},
+		{Source: "bca", Id: "unrelated_2", Type: "DEBIT", Amount: 10, Date: "2025-01-04"},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator := NewSummaryAggregator(2)
+	results := map[string]ReconTransaction{}
+	for rt := range newService.PassThroughSummary(reconChan, aggregator) {
+		results[rt.Id] = rt
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		id        string
+		matched   bool
+		rule      string
+		residual  float64
+		remark    string
+		otherId   string
+		unmatched bool
+	}{
+		{id: "transfer_1", matched: true, rule: MatchRulePartial, residual: 40, remark: "Partially covers installment, 40.00 of 100.00 left", otherId: "installment"},
+		{id: "transfer_2", matched: true, rule: MatchRulePartial, residual: 0, remark: "Partially covers installment, 0.00 of 100.00 left", otherId: "installment"},
+		{id: "split_1", matched: true, rule: MatchRulePartial, residual: 20, otherId: "payout"},
+		{id: "payout", rule: MatchRulePartial, residual: 20, remark: "Partially matched by 1 external transactions, 20.00 of 50.00 left", unmatched: true},
+		{id: "too_large", unmatched: true},
+		{id: "bca_exact", matched: true, rule: MatchRuleAmount, otherId: "exact"},
+		{id: "va_50_a", matched: true, rule: MatchRulePartial, residual: 50, otherId: "repayment"},
+		{id: "va_50_b", matched: true, rule: MatchRulePartial, residual: 0, otherId: "repayment"},
+		{id: "va_70", unmatched: true},
+		{id: "fee", unmatched: true},
+		{id: "unrelated_1", unmatched: true},
+		{id: "unrelated_2", unmatched: true},
+	}
+
+	for _, testCase := range testCases {
+		rt, ok := results[testCase.id]
+		if !ok {
+			t.Errorf("[%s] Expected a result, got %+v", testCase.id, results)
+			continue
+		}
+
+		if rt.IsMatched != testCase.matched || rt.IsUnmatched() != testCase.unmatched || rt.MatchRule != testCase.rule || rt.Residual != testCase.residual {
+			t.Errorf("[%s] Unexpected result %+v", testCase.id, rt)
+		}
+		if testCase.remark != "" && rt.Remark != testCase.remark {
+			t.Errorf("[%s] Expected remark %q, got %q", testCase.id, testCase.remark, rt.Remark)
+		}
+		if testCase.matched && rt.OtherTransaction.Id != testCase.otherId {
+			t.Errorf("[%s] Expected paired with %s, got %s", testCase.id, testCase.otherId, rt.OtherTransaction.Id)
+		}
+	}
+
+	for _, id := range []string{"installment", "repayment"} {
+		if _, ok := results[id]; ok {
+			t.Errorf("Expected the fully covered %s not reported as a break", id)
+		}
+	}
+
+	summary := aggregator.Snapshot()
+	if summary.TotalMatched != 3 || summary.MatchedByRule[MatchRulePartial] != 2 || summary.TotalPartialLegs != 5 || summary.TotalDiscrepancy != 0 {
+		t.Errorf("Expected 3 pairs, 2 of them internals fully covered by 5 partial legs, without discrepancy, got %+v", summary)
+	}
+	if summary.TotalMismatched != 6 || summary.TotalUnmatchedAmount != 250 {
+		t.Errorf("Expected the payout residual and 5 transactions unmatched for 250, got %d %.2f", summary.TotalMismatched, summary.TotalUnmatchedAmount)
+	}
+	if amartha := summary.BySource["amartha"]; amartha.MatchedCount != 3 || amartha.MatchedAmount != 255 || amartha.UnmatchedAmount != 60 {
+		t.Errorf("Expected the internal side counted once with its covered amount, got %+v", amartha)
+	}
+	if processed := summary.TotalProcessed(); processed != len(transactions) {
+		t.Errorf("Expected %d processed, got %d", len(transactions), processed)
+	}
+}
+
+func TestReconService_PartialMatchingDeterministic(t *testing.T) {
+	transactions := []model.Transaction{
+		// both internals could take the same transfer
+		{Source: "amartha", Id: "installment_1", VirtualAccount: "8808001", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "amartha", Id: "installment_2", VirtualAccount: "8808001", Type: "CREDIT", Amount: 90, Date: "2025-01-01"},
+		{Source: "bca", Id: "transfer_1", VirtualAccount: "8808001", Type: "CREDIT", Amount: 60, Date: "2025-01-01"},
+		{Source: "bca", Id: "transfer_2", VirtualAccount: "8808001", Type: "CREDIT", Amount: 20, Date: "2025-01-01"},
+	}
+
+	for run := 0; run < 20; run++ {
+		newService, _ := NewReconService(NewReconServiceOpts{
+			Ctx:             context.Background(),
+			PartialMatching: true,
+		})
+		newService.internalSource = "amartha"
+
+		inChan := make(chan model.Transaction, len(transactions))
+		for _, txn := range transactions {
+			inChan <- txn
+		}
+		close(inChan)
+
+		reconChan, err := newService.Reconcile(inChan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results := map[string]ReconTransaction{}
+		for rt := range reconChan {
+			results[rt.Id] = rt
+		}
+		if err := newService.Wait(); err != nil {
+			t.Fatal(err)
+		}
+
+		// taken by id order, installment_1 first
+		for _, id := range []string{"transfer_1", "transfer_2"} {
+			if rt := results[id]; rt.OtherTransaction.Id != "installment_1" {
+				t.Fatalf("[run %d] Expected %s to cover installment_1, got %+v", run, id, rt)
+			}
+		}
+		if rt := results["installment_2"]; rt.MatchRule != "" || !rt.IsUnmatched() {
+			t.Fatalf("[run %d] Expected installment_2 left unmatched, got %+v", run, rt)
+		}
+	}
+}
+
+func TestReconService_PartialResidualCarriedForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "open_items.jsonl")
+
+	run := func(transactions []model.Transaction) []ReconTransaction {
+		newService, _ := NewReconService(NewReconServiceOpts{
+			Ctx:             context.Background(),
+			PartialMatching: true,
+			OpenItemsPath:   path,
+		})
+		newService.internalSource = "amartha"
+
+		inChan := make(chan model.Transaction, len(transactions))
+		for _, txn := range transactions {
+			inChan <- txn
+		}
+		close(inChan)
+
+		reconChan, err := newService.Reconcile(inChan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results := []ReconTransaction{}
+		for rt := range newService.PassThroughOpenItems(reconChan) {
+			results = append(results, rt)
+		}
+		if err := newService.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	run([]model.Transaction{
+		{Source: "amartha", Id: "payout", Type: "DEBIT", Amount: 50, Date: "2025-01-01"},
+		{Source: "bca", Id: "split_1", Reference: "payout", Type: "DEBIT", Amount: 30, Date: "2025-01-01"},
+		{Source: "amartha", Id: "installment", VirtualAccount: "8808001", Type: "CREDIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "bca", Id: "transfer_1", VirtualAccount: "8808001", Type: "CREDIT", Amount: 40, Date: "2025-01-01"},
+	})
+
+	items, err := LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	residuals := map[string]float64{}
+	for _, item := range items {
+		if item.CarriedResidual {
+			residuals[item.Id] = item.Amount
+		}
+	}
+	if len(items) != 2 || residuals["payout"] != 20 || residuals["installment"] != 60 {
+		t.Fatalf("Expected the residuals of 20 and 60 carried forward, got %+v", items)
+	}
+
+	// days later, the rest of the payout names it, the installment is paid in two transfers to its virtual account
+	results := run([]model.Transaction{
+		{Source: "bca", Id: "unrelated", Type: "DEBIT", Amount: 20, Date: "2025-01-05"},
+		{Source: "bca", Id: "split_2", Reference: "payout", Type: "DEBIT", Amount: 20, Date: "2025-01-06"},
+		{Source: "bca", Id: "transfer_2", VirtualAccount: "8808001", Type: "CREDIT", Amount: 25, Date: "2025-01-05"},
+		{Source: "bca", Id: "transfer_3", VirtualAccount: "8808001", Type: "CREDIT", Amount: 35, Date: "2025-01-06"},
+	})
+
+	byId := map[string]ReconTransaction{}
+	for _, rt := range results {
+		byId[rt.Id] = rt
+	}
+	if len(results) != 4 {
+		t.Errorf("Expected 4 results, got %+v", results)
+	}
+
+	split := byId["split_2"]
+	if !split.IsMatched || split.OtherTransaction.Id != "payout" || split.MatchRule != MatchRuleOpenItem {
+		t.Errorf("Expected split_2 to cover the residual of payout, got %+v", split)
+	}
+	if remark := "Covers the residual of open item payout of amartha from 2025-01-01"; split.Remark != remark {
+		t.Errorf("Expected remark %q, got %q", remark, split.Remark)
+	}
+
+	if rt := byId["unrelated"]; !rt.IsUnmatched() {
+		t.Errorf("Expected the unrelated debit of the same amount left unmatched, got %+v", rt)
+	}
+
+	for id, residual := range map[string]float64{"transfer_3": 25, "transfer_2": 0} {
+		rt := byId[id]
+		if !rt.IsMatched || rt.MatchRule != MatchRulePartial || rt.OtherTransaction.Id != "installment" || rt.Residual != residual {
+			t.Errorf("Expected %s to cover part of the installment residual leaving %.2f, got %+v", id, residual, rt)
+		}
+	}
+
+	items, err = LoadOpenItems(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Id != "unrelated" {
+		t.Errorf("Expected only the unrelated debit left open, got %+v", items)
+	}
+}
+
+func TestReconService_PartialMatchingSameIdSplit(t *testing.T) {
+	transactions := []model.Transaction{
+		// the bank splits a payout under the id of the internal one
+		{Source: "amartha", Id: "payout_1", Type: "DEBIT", Amount: 100, Date: "2025-01-01"},
+		{Source: "bca", Id: "payout_1", Type: "DEBIT", Amount: 50, Date: "2025-01-01", SourceRow: 1},
+		{Source: "bca", Id: "payout_1", Type: "DEBIT", Amount: 50, Date: "2025-01-01", SourceRow: 2},
+		// the legs read before the internal one
+		{Source: "bca", Id: "payout_2", Type: "DEBIT", Amount: 70, Date: "2025-01-01", SourceRow: 3},
+		{Source: "bca", Id: "payout_2", Type: "DEBIT", Amount: 20, Date: "2025-01-01", SourceRow: 4},
+		{Source: "amartha", Id: "payout_2", Type: "DEBIT", Amount: 100, Date: "2025-01-01"},
+		// a row read twice without an internal one to split is still a duplicate
+		{Source: "bca", Id: "twice", Type: "CREDIT", Amount: 5, Date: "2025-01-01", SourceRow: 5},
+		{Source: "bca", Id: "twice", Type: "CREDIT", Amount: 5, Date: "2025-01-01", SourceRow: 6},
+	}
+
+	for _, shardCount := range []int{1, 2} {
+		newService, _ := NewReconService(NewReconServiceOpts{
+			Ctx:             context.Background(),
+			PartialMatching: true,
+			ShardCount:      shardCount,
+		})
+		newService.internalSource = "amartha"
+
+		inChan := make(chan model.Transaction, len(transactions))
+		for _, txn := range transactions {
+			inChan <- txn
+		}
+		close(inChan)
+
+		reconChan, err := newService.Reconcile(inChan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		aggregator := NewSummaryAggregator(1)
+		results := []ReconTransaction{}
+		for rt := range newService.PassThroughSummary(reconChan, aggregator) {
+			results = append(results, rt)
+		}
+		if err := newService.Wait(); err != nil {
+			t.Fatal(err)
+		}
+
+		partial := map[string]float64{}
+		errorCount := 0
+		for _, rt := range results {
+			switch {
+			case rt.IsError:
+				errorCount += 1
+				if rt.Id != "twice" {
+					t.Errorf("[shards %d] Expected only the row read twice as a duplicate, got %+v", shardCount, rt)
+				}
+			case rt.IsMatched && rt.MatchRule == MatchRulePartial:
+				partial[rt.OtherTransaction.Id] += rt.Amount
+			case rt.Source == "amartha":
+				if rt.Id != "payout_2" || rt.Residual != 10 {
+					t.Errorf("[shards %d] Expected only the residual of payout_2 left, got %+v", shardCount, rt)
+				}
+			}
+		}
+
+		if errorCount != 1 || partial["payout_1"] != 100 || partial["payout_2"] != 90 {
+			t.Errorf("[shards %d] Expected both payouts covered by their legs, got %v with %d errors: %+v", shardCount, partial, errorCount, results)
+		}
+
+		summary := aggregator.Snapshot()
+		if summary.TotalDiscrepancy != 0 || summary.MatchedByRule[MatchRuleId] != 0 {
+			t.Errorf("[shards %d] Expected no id match with a discrepancy, got %+v", shardCount, summary)
+		}
+	}
+}
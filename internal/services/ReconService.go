@@ -1,9 +1,13 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"math"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/kevin-luvian/amartha-recon/internal/model"
@@ -13,57 +17,195 @@ import (
 	"github.com/kevin-luvian/amartha-recon/pkg/storage"
 )
 
+// ReconSummary is plain data, aggregate it with a SummaryAggregator
 type ReconSummary struct {
-	TotalMatched          int
-	TotalMismatched       int
-	TotalDiscrepancy      float64
-	TotalMismatchBySource map[string]int
+	TotalMatched          int                         `json:"total_matched"`          // matched pairs, a pair counts once, an internal covered by partial legs once when fully covered
+	TotalMismatched       int                         `json:"total_mismatched"`       // unmatched transactions, errors excluded
+	TotalErrors           int                         `json:"total_errors"`           // records that failed to parse or were rejected
+	TotalSelfCancelled    int                         `json:"total_self_cancelled"`   // legs of reversal pairs netted within their source
+	TotalPartialLegs      int                         `json:"total_partial_legs"`     // external transactions covering part of an internal one, see MatchRulePartial
+	TotalDiscrepancy      float64                     `json:"total_discrepancy"`      // absolute amount difference of the matched pairs
+	TotalUnmatchedAmount  float64                     `json:"total_unmatched_amount"` // amount of the unmatched transactions
+	TotalMismatchBySource map[string]int              `json:"total_mismatch_by_source"`
+	ErrorsByCode          map[string]int              `json:"errors_by_code"`  // errors by model.ErrorCode
+	MatchedByRule         map[string]int              `json:"matched_by_rule"` // matched pairs by MatchRule
+	BySource              map[string]SummaryBreakdown `json:"by_source"`
+	ByType                map[string]SummaryBreakdown `json:"by_type"`                     // errors without a type are left out
+	DateFrom              string                      `json:"date_from,omitempty"`         // earliest date of a reconciled transaction
+	DateTo                string                      `json:"date_to,omitempty"`           // latest date of a reconciled transaction
+	AgingCountByBucket    map[string]int              `json:"aging_count_by_bucket"`       // unmatched breaks by aging bucket, filled when aging is configured
+	AgingAmountByBucket   map[string]float64          `json:"aging_amount_by_bucket"`      // unmatched amount by aging bucket
+	UntrustedSources      map[string][]string         `json:"untrusted_sources,omitempty"` // statements failing balance verification by source, filled when a balance check is configured
 }
 
 func NewReconSummary() *ReconSummary {
 	return &ReconSummary{
 		TotalMismatchBySource: make(map[string]int),
+		ErrorsByCode:          make(map[string]int),
+		MatchedByRule:         make(map[string]int),
+		BySource:              make(map[string]SummaryBreakdown),
+		ByType:                make(map[string]SummaryBreakdown),
+		AgingCountByBucket:    make(map[string]int),
+		AgingAmountByBucket:   make(map[string]float64),
+		UntrustedSources:      make(map[string][]string),
 	}
 }
 
+// rules a matched pair was found by
+const (
+	MatchRuleId             = "ID"              // same date, type and id
+	MatchRuleAmount         = "AMOUNT"          // same date, type and amount
+	MatchRuleDate           = "DATE"            // only transaction of its date and type on both sides
+	MatchRuleOpenItem       = "OPEN_ITEM"       // same type and id as an open item of a previous run
+	MatchRulePartial        = "PARTIAL"         // external covering part of an internal of the same date and type
+	MatchRuleVirtualAccount = "VIRTUAL_ACCOUNT" // same virtual account, type and amount within the window
+)
+
 type ReconTransaction struct {
 	model.Transaction
 	OtherTransaction model.Transaction
 	IsMatched        bool
 	IsError          bool
+	IsSelfCancelled  bool    // one leg of a reversal pair within its source, OtherTransaction is the other leg
+	MatchRule        string  // set on matched pairs, and PARTIAL on the break of a partially matched internal transaction
+	Residual         float64 // internal amount left uncovered by partial matching, set with MatchRulePartial
 	Remark           string
 }
 
+// IsUnmatched is true for a break: neither matched, self-cancelled nor an error
+func (t ReconTransaction) IsUnmatched() bool {
+	return !t.IsMatched && !t.IsError && !t.IsSelfCancelled
+}
+
+// OpenAmount is the amount of a break left to match, the residual of a partially matched one
+func (t ReconTransaction) OpenAmount() float64 {
+	if t.MatchRule == MatchRulePartial {
+		return t.Residual
+	}
+	return t.Amount
+}
+
 type ReconCsvDetail struct {
-	Source      string
-	CsvFilepath string
-	Parser      parser.IParseAble[model.Transaction]
+	Source        string
+	CsvFilepath   string // file, glob pattern or directory, .gz and .zip are decompressed
+	Parser        parser.IParseAble[model.Transaction]
+	Reader        ingester.IRecordReader // optional, defaults to the service CsvIngester
+	PreserveOrder bool                   // emit transactions in file order, parsing stays parallel
+}
+
+func (d ReconCsvDetail) getReader(defaultReader ingester.IRecordReader) ingester.IRecordReader {
+	if d.Reader != nil {
+		return d.Reader
+	}
+	return defaultReader
+}
+
+// parse keeps the file provenance of the record on the parsed transaction
+func (d ReconCsvDetail) parse(record map[string]string) model.Transaction {
+	transaction := d.Parser.Parse(record)
+
+	transaction.SourceFile = record[ingester.RecordFileKey]
+	if transaction.SourceFile == "" {
+		transaction.SourceFile = d.CsvFilepath
+	}
+
+	if row, err := strconv.Atoi(record[ingester.RecordRowKey]); err == nil {
+		transaction.SourceRow = row
+	}
+
+	return transaction
 }
 
 type ReconService struct {
-	Ctx                  context.Context
+	Ctx                  context.Context // pipeline context, cancelled on the first error of any stage
+	group                *pipeline.Group
 	CsvIngester          ingester.ICsvIngester
 	FilterDateRange      []string
 	filterDateRangeEpoch []int64
 	internalSource       string
 	externalSources      []string
-	internalTable        storage.HashTable
-	externalTable        storage.HashTable
+	workerCount          int
+	bufferSize           int
+	metrics              *pipeline.Metrics
+	shardCount           int
+	spillDir             string
+	spillRunSize         int
+	storagePath          string
+	openItemsPath        string
+	openItemsTemp        string // ledger written by PassThroughOpenItems, renamed by Wait
+	netting              *NettingConfig
+	partialMatching      bool
+	virtualAccountWindow int
+	startedAt            time.Time
+	inputs               []*runInput
 }
 
+const (
+	defaultWorkerCount = 4
+	defaultBufferSize  = 10
+)
+
 type NewReconServiceOpts struct {
-	Ctx             context.Context
-	CsvIngester     ingester.ICsvIngester
-	FilterDateRange []string
+	Ctx                      context.Context
+	CsvIngester              ingester.ICsvIngester
+	FilterDateRange          []string
+	WorkerCount              int               // parse workers per source, defaults to 4
+	BufferSize               int               // buffer of every channel between stages, defaults to 10
+	Metrics                  *pipeline.Metrics // optional, records items and blocked time of every stage
+	ShardCount               int               // reconcile with this many matchers partitioned by date, 0 or 1 runs a single matcher
+	SpillDir                 string            // reconcile out of core, sorting transactions by date into run files in this directory, ShardCount is ignored
+	SpillRunSize             int               // transactions held in memory per sorted run, defaults to 100000
+	StoragePath              string            // keep unmatched transactions in a bbolt file instead of memory, cleared when a run starts, ignored with SpillDir
+	OpenItemsPath            string            // ledger of open items carried between runs, see PassThroughOpenItems
+	Netting                  *NettingConfig    // net reversal pairs within one source before matching, nil disables, not supported with SpillDir or StoragePath
+	PartialMatching          bool              // cover an internal transaction with several smaller related external ones of its date and type
+	VirtualAccountWindowDays int               // days between transactions matched by virtual account, 0 is the same date only, requires a single matcher above 0
 }
 
 func NewReconService(opts NewReconServiceOpts) (*ReconService, error) {
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	group, groupCtx := pipeline.WithGroup(ctx)
 	service := &ReconService{
-		Ctx:             opts.Ctx,
-		CsvIngester:     opts.CsvIngester,
-		FilterDateRange: opts.FilterDateRange,
-		internalTable:   *storage.NewHashTable(),
-		externalTable:   *storage.NewHashTable(),
+		Ctx:                  groupCtx,
+		group:                group,
+		CsvIngester:          opts.CsvIngester,
+		FilterDateRange:      opts.FilterDateRange,
+		workerCount:          opts.WorkerCount,
+		bufferSize:           opts.BufferSize,
+		metrics:              opts.Metrics,
+		shardCount:           opts.ShardCount,
+		spillDir:             opts.SpillDir,
+		spillRunSize:         opts.SpillRunSize,
+		storagePath:          opts.StoragePath,
+		openItemsPath:        opts.OpenItemsPath,
+		netting:              opts.Netting,
+		partialMatching:      opts.PartialMatching,
+		virtualAccountWindow: opts.VirtualAccountWindowDays,
+		startedAt:            time.Now(),
+	}
+
+	if service.workerCount <= 0 {
+		service.workerCount = defaultWorkerCount
+	}
+	if service.bufferSize <= 0 {
+		service.bufferSize = defaultBufferSize
+	}
+	if service.spillRunSize <= 0 {
+		service.spillRunSize = defaultSpillRunSize
+	}
+
+	// netting holds every transaction until the input ends, which defeats the memory bound of spilling or bbolt storage
+	if service.netting != nil && (service.spillDir != "" || service.storagePath != "") {
+		return service, fmt.Errorf("netting holds the whole input in memory, unset Netting or SpillDir and StoragePath")
+	}
+
+	// shards and spilled dates split the transactions by date, a window across dates would miss candidates
+	if service.virtualAccountWindow > 0 && (service.shardCount > 1 || service.spillDir != "") {
+		return service, fmt.Errorf("virtual account window of %d days requires a single matcher, unset ShardCount and SpillDir", service.virtualAccountWindow)
 	}
 
 	if len(opts.FilterDateRange) == 2 {
@@ -83,204 +225,362 @@ func NewReconService(opts NewReconServiceOpts) (*ReconService, error) {
 	return service, nil
 }
 
-func (r *ReconService) ReadInternalCsv(detail ReconCsvDetail) (<-chan model.Transaction, error) {
-	outputChan := make(chan model.Transaction, 10)
+// StageOptions configures a pipeline stage named name with the service buffer size and metrics
+func (r *ReconService) StageOptions(name string) []pipeline.StageOption {
+	return []pipeline.StageOption{
+		pipeline.WithBufferSize(r.bufferSize),
+		pipeline.WithMetrics(r.metrics.Stage(name)),
+	}
+}
 
+func (r *ReconService) ReadInternalCsv(detail ReconCsvDetail) (<-chan model.Transaction, error) {
 	if r.internalSource != "" {
-		return outputChan, fmt.Errorf("only one internal csv source expected")
+		return make(chan model.Transaction), fmt.Errorf("only one internal csv source expected")
 	}
 	r.internalSource = detail.Source
 
-	readChan, err := r.CsvIngester.Read(r.Ctx, detail.CsvFilepath)
-	if err != nil {
-		return outputChan, err
-	}
-
-	pipeline.GetTransformerChans(
-		readChan,
-		outputChan,
-		4,
-		detail.Parser.Parse,
-	)
-
-	return outputChan, nil
+	return r.readSource(detail)
 }
 
 func (r *ReconService) ReadExternalCsv(detail ReconCsvDetail) (<-chan model.Transaction, error) {
 	r.externalSources = append(r.externalSources, detail.Source)
-	outputChan := make(chan model.Transaction, 10)
 
-	readChan, err := r.CsvIngester.Read(r.Ctx, detail.CsvFilepath)
+	return r.readSource(detail)
+}
+
+func (r *ReconService) readSource(detail ReconCsvDetail) (<-chan model.Transaction, error) {
+	outputChan := make(chan model.Transaction, r.bufferSize)
+
+	input := newRunInput(detail)
+	readCtx := ingester.WithFileChecksums(r.Ctx, input.read)
+
+	readChan, err := detail.getReader(r.CsvIngester).Read(readCtx, detail.CsvFilepath)
 	if err != nil {
 		return outputChan, err
 	}
+	r.inputs = append(r.inputs, input)
+	parse := func(record map[string]string) model.Transaction {
+		transaction := detail.parse(record)
+		input.count(transaction.SourceFile)
+		return transaction
+	}
+
+	if detail.PreserveOrder {
+		pipeline.GetOrderedTransformerChans(
+			r.Ctx,
+			readChan,
+			outputChan,
+			r.workerCount,
+			r.bufferSize,
+			parse,
+			r.StageOptions("parse:"+detail.Source)...,
+		)
+		return outputChan, nil
+	}
 
 	pipeline.GetTransformerChans(
+		r.Ctx,
 		readChan,
 		outputChan,
-		4,
-		detail.Parser.Parse,
+		r.workerCount,
+		parse,
+		r.StageOptions("parse:"+detail.Source)...,
 	)
 
-	return outputChan, err
+	return outputChan, nil
 }
 
 func (r *ReconService) Reconcile(transactionChan <-chan model.Transaction) (<-chan ReconTransaction, error) {
-	outChan := make(chan ReconTransaction, 10)
+	outChan := make(chan ReconTransaction, r.bufferSize)
 
 	if r.internalSource == "" {
 		return outChan, fmt.Errorf("internal source not set")
 	}
 
-	go func() {
-		defer close(outChan)
+	metrics := r.metrics.Stage("reconcile")
+	emit := func(reconTransaction ReconTransaction) bool {
+		return pipeline.SendMeasured(r.Ctx, outChan, reconTransaction, metrics)
+	}
 
-		for transaction := range transactionChan {
-			// pass error records
-			if transaction.ParseError != nil {
-				outChan <- ReconTransaction{
-					Transaction: transaction,
-					IsError:     true,
-					Remark:      transaction.ParseError.Error(),
-				}
-				continue
-			}
+	if r.openItemsPath != "" {
+		items, checksum, err := loadOpenItems(r.openItemsPath)
+		if err != nil {
+			return outChan, err
+		}
+		if checksum != nil {
+			r.inputs = append(r.inputs, newOpenItemsInput(r.openItemsPath, *checksum, len(items)))
+		}
+		transactionChan = r.carryForward(transactionChan, newOpenItems(r.internalSource, items), emit)
+	}
 
-			if transaction.Source == r.internalSource {
-				r.internalTable.Put(transaction)
-				reconTransaction, ok := r.processInternalMatching(transaction)
-				if ok {
-					outChan <- reconTransaction
-				}
-			} else {
-				r.externalTable.Put(transaction)
-				reconTransaction, ok := r.processExternalMatching(transaction)
-				if ok {
-					outChan <- reconTransaction
-				}
-			}
+	// after the open items, so a carried item can be netted against its reversal
+	if r.netting != nil {
+		transactionChan = r.netReversals(transactionChan, *r.netting, emit)
+	}
+
+	if r.spillDir != "" {
+		r.reconcileSpilled(transactionChan, outChan, metrics, emit)
+		return outChan, nil
+	}
+
+	var store *storage.BoltStore
+	if r.storagePath != "" {
+		var err error
+		store, err = storage.OpenBoltStore(r.storagePath)
+		if err != nil {
+			return outChan, fmt.Errorf("failed to open storage: %w", err)
 		}
+		// rows left by an earlier run would be reported again, the open items ledger carries them instead
+		if err := store.Clear(); err != nil {
+			store.Close()
+			return outChan, fmt.Errorf("failed to clear storage: %w", err)
+		}
+	}
 
-		for _, externalTransaction := range r.externalTable.Table {
-			// Last matching by date, if contains exactly one transaction
-			transaction := externalTransaction.(model.Transaction)
+	if r.shardCount > 1 {
+		r.reconcileSharded(transactionChan, outChan, store, metrics, emit)
+		return outChan, nil
+	}
 
-			key, isMatch := r.internalTable.IsPathContainsOneValue(transaction.GetKeySearchByDate())
-			if isMatch {
-				_, isMatch = r.externalTable.IsPathContainsOneValue(transaction.GetKeySearchByDate())
-			}
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+		if store != nil {
+			defer store.Close()
+		}
 
-			if isMatch {
-				// match exactly one transaction in internal and external by date, flag as match
-				intTransaction := r.internalTable.GetById(key)
-				r.internalTable.Remove(intTransaction)
+		matcher, err := r.openMatcher(store, nil)
+		if err != nil {
+			return err
+		}
 
-				outChan <- ReconTransaction{
-					Transaction:      transaction,
-					OtherTransaction: intTransaction.(model.Transaction),
-					IsMatched:        true,
-				}
-				continue
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, metrics)
+			if !ok {
+				break
 			}
 
-			outChan <- ReconTransaction{
-				Transaction: externalTransaction.(model.Transaction),
-				IsMatched:   false,
-				Remark:      "No matching internal transaction found",
+			if err := r.reconcileOne(matcher, transaction, emit); err != nil {
+				return err
 			}
 		}
 
-		for _, internalTransaction := range r.internalTable.Table {
-			outChan <- ReconTransaction{
-				Transaction: internalTransaction.(model.Transaction),
-				IsMatched:   false,
-				Remark:      "No matching external transaction found",
-			}
-		}
-	}()
+		return r.reconcileRemaining(matcher, emit)
+	})
 
 	return outChan, nil
 }
 
-func (r *ReconService) processInternalMatching(transaction model.Transaction) (ReconTransaction, bool) {
-	extTransaction := r.externalTable.GetById(transaction.GetHashById())
+// openMatcher keeps the matcher tables in store when set. Every shard shares
+// the same two tables, owns keeps the keys of the shard out of them.
+func (r *ReconService) openMatcher(store *storage.BoltStore, owns func(key []byte) bool) (*matcher, error) {
+	if store == nil {
+		return r.configureMatcher(newMatcher(r.internalSource)), nil
+	}
+
+	codec := storage.GobCodec[model.Transaction]{}
+
+	internalTable, err := store.Table("internal", codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage table: %w", err)
+	}
 
-	if extTransaction == nil {
-		extTransaction = r.externalTable.GetFirstMatchByPath(transaction.GetKeySearchByAmount())
+	externalTable, err := store.Table("external", codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage table: %w", err)
 	}
 
-	if extTransaction == nil {
-		// No match found
-		return ReconTransaction{}, false
+	if owns != nil {
+		internalTable, externalTable = internalTable.Partition(owns), externalTable.Partition(owns)
 	}
 
-	// Matched and remove
-	r.internalTable.Remove(transaction)
-	r.externalTable.Remove(extTransaction)
+	return r.configureMatcher(newMatcherWithTables(r.internalSource, internalTable, externalTable)), nil
+}
 
-	return ReconTransaction{
-		Transaction:      transaction,
-		OtherTransaction: extTransaction.(model.Transaction),
-		IsMatched:        true,
-	}, true
+// configureMatcher applies the optional matching rules of the service to matcher
+func (r *ReconService) configureMatcher(matcher *matcher) *matcher {
+	matcher.partial = r.partialMatching
+	matcher.virtualAccounts = newVirtualAccountIndex(r.virtualAccountWindow)
+	return matcher
 }
 
-func (r *ReconService) processExternalMatching(transaction model.Transaction) (ReconTransaction, bool) {
-	intTransaction := r.internalTable.GetById(transaction.GetHashById())
+// reconcileOne passes error records and matches the rest
+func (r *ReconService) reconcileOne(matcher *matcher, transaction model.Transaction, emit func(ReconTransaction) bool) error {
+	// pass error records
+	if transaction.ParseError != nil {
+		if !emit(errorReconTransaction(transaction)) {
+			return context.Cause(r.Ctx)
+		}
+		return nil
+	}
+
+	reconTransaction, ok := matcher.match(transaction)
+	if err := matcher.err(); err != nil {
+		return fmt.Errorf("failed to match transaction: %w", err)
+	}
 
-	if intTransaction == nil {
-		intTransaction = r.internalTable.GetFirstMatchByPath(transaction.GetKeySearchByAmount())
+	if ok && !emit(reconTransaction) {
+		return context.Cause(r.Ctx)
 	}
 
-	if intTransaction == nil {
-		// No match found
-		return ReconTransaction{}, false
+	return nil
+}
+
+// reconcileRemaining runs the end of input matching once every transaction was read
+func (r *ReconService) reconcileRemaining(matcher *matcher, emit func(ReconTransaction) bool) error {
+	if r.Ctx.Err() != nil || !matcher.reconcileRemaining(emit) {
+		return context.Cause(r.Ctx)
 	}
 
-	// Matched and remove
-	r.internalTable.Remove(intTransaction)
-	r.externalTable.Remove(transaction)
+	if err := matcher.err(); err != nil {
+		return fmt.Errorf("failed to match transaction: %w", err)
+	}
 
+	return nil
+}
+
+func errorReconTransaction(transaction model.Transaction) ReconTransaction {
 	return ReconTransaction{
-		Transaction:      transaction,
-		OtherTransaction: intTransaction.(model.Transaction),
-		IsMatched:        true,
-	}, true
+		Transaction: transaction,
+		IsError:     true,
+		Remark:      transaction.ParseError.Error(),
+	}
 }
 
-func (r *ReconService) PassThroughSummary(reconTransactionChan <-chan ReconTransaction, summary *ReconSummary) <-chan ReconTransaction {
-	return pipeline.TransformChan(reconTransactionChan, func(t ReconTransaction) (ReconTransaction, bool) {
-		if t.IsMatched {
-			// Count both internal and external matched transactions
-			summary.TotalMatched += 2
-			summary.TotalDiscrepancy += math.Abs(t.Amount - t.OtherTransaction.Amount)
-		} else {
-			summary.TotalMismatched += 1
-			summary.TotalMismatchBySource[t.Source] += 1
+// reconcileSharded partitions transactions by date over shardCount matchers.
+//
+// Every matching rule looks at transactions of a single date only, so a shard
+// owning a date sees every candidate the single matcher would, in the same
+// order, and finds the same matches.
+func (r *ReconService) reconcileSharded(
+	transactionChan <-chan model.Transaction,
+	outChan chan ReconTransaction,
+	store *storage.BoltStore,
+	metrics *pipeline.StageMetrics,
+	emit func(ReconTransaction) bool,
+) {
+	shardChans := make([]chan model.Transaction, r.shardCount)
+	for i := range shardChans {
+		shardChans[i] = make(chan model.Transaction, r.bufferSize)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(shardChans))
+
+	pipeline.Go(r.Ctx, func() error {
+		defer func() {
+			for _, shardChan := range shardChans {
+				close(shardChan)
+			}
+		}()
+
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, metrics)
+			if !ok {
+				return context.Cause(r.Ctx)
+			}
+
+			if !pipeline.Send(r.Ctx, shardChans[shardByDate(transaction.Date, len(shardChans))], transaction) {
+				return context.Cause(r.Ctx)
+			}
 		}
+	})
+
+	for i, shardChan := range shardChans {
+		pipeline.Go(r.Ctx, func() error {
+			defer wg.Done()
+
+			matcher, err := r.openMatcher(store, func(key []byte) bool {
+				// keys start with the date, see model.Transaction.GetHashById
+				date, _, _ := bytes.Cut(key, []byte("|"))
+				return shardByDate(string(date), len(shardChans)) == i
+			})
+			if err != nil {
+				return err
+			}
+
+			for transaction := range shardChan {
+				if err := r.reconcileOne(matcher, transaction, emit); err != nil {
+					return err
+				}
+			}
 
-		return t, true
+			return r.reconcileRemaining(matcher, emit)
+		})
+	}
+
+	pipeline.Go(r.Ctx, func() error {
+		wg.Wait()
+		close(outChan)
+		if store != nil {
+			return store.Close()
+		}
+		return nil
 	})
 }
 
+func shardByDate(date string, shardCount int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(date))
+	return int(hash.Sum32() % uint32(shardCount))
+}
+
 func (r *ReconService) FilterMismatched(t ReconTransaction) (ReconTransaction, bool) {
-	return t, !t.IsMatched
+	return t, t.IsUnmatched() || t.IsError
 }
 
 func (r *ReconService) WriteToCsv(filepath string, reconTransactionChan <-chan ReconTransaction) error {
-	recordChan := pipeline.TransformChan(reconTransactionChan, func(rt ReconTransaction) (map[string]string, bool) {
+	csvHeader := []string{"source", "id", "type", "amount", "date", "remark", "error_code"}
+
+	return r.writeCsv(filepath, csvHeader, reconTransactionChan, func(rt ReconTransaction) map[string]string {
 		return map[string]string{
-			"source": rt.Source,
-			"id":     rt.Id,
-			"type":   rt.Type,
-			"amount": fmt.Sprintf("%.2f", rt.Amount),
-			"date":   rt.Date,
-			"remark": rt.Remark,
-		}, true
+			"source":     rt.Source,
+			"id":         rt.Id,
+			"type":       rt.Type,
+			"amount":     fmt.Sprintf("%.2f", rt.Amount),
+			"date":       rt.Date,
+			"remark":     rt.Remark,
+			"error_code": errorCodes(rt.Transaction),
+		}
 	})
+}
+
+// errorCodes joins the error codes of t for the error_code column, a row failing on several fields lists each
+func errorCodes(t model.Transaction) string {
+	codes := make([]string, 0, 1)
+	for _, code := range t.ErrorCodes() {
+		codes = append(codes, string(code))
+	}
+	return strings.Join(codes, ";")
+}
+
+// writeCsv writes every record of reconTransactionChan to filepath, the other writers report errors the same way.
+//
+// The stream ends early on the first error of any stage, so the error of the
+// run is returned instead of a partial success. A stage failing once the stream
+// ended, like a reader closing its files, is only caught by Wait. Writers run
+// as stages of the group themselves and cannot wait on it, callers must call
+// Wait before trusting the output.
+func (r *ReconService) writeCsv(
+	filepath string,
+	csvHeader []string,
+	reconTransactionChan <-chan ReconTransaction,
+	toRecord func(ReconTransaction) map[string]string,
+) error {
+	recordChan := pipeline.TransformChan(r.Ctx, reconTransactionChan, func(rt ReconTransaction) (map[string]string, bool) {
+		return toRecord(rt), true
+	}, r.StageOptions("write")...)
+
+	if err := r.CsvIngester.Write(r.Ctx, filepath, csvHeader, recordChan); err != nil {
+		r.group.Fail(err)
+	}
+
+	return r.group.Err()
+}
 
-	csvHeader := []string{"source", "id", "type", "amount", "date", "remark"}
-	return r.CsvIngester.Write(r.Ctx, filepath, csvHeader, recordChan)
+// Wait blocks until every stage started by the service returned, and returns the first error of the run.
+// Only then the open items ledger of a successful run replaces the previous one.
+func (r *ReconService) Wait() error {
+	return r.commitOpenItems(r.group.Wait())
 }
 
 func (r *ReconService) FilterByDate(record model.Transaction) (model.Transaction, bool) {
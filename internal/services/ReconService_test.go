@@ -7,10 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/kevin-luvian/amartha-recon/internal/model"
 	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
 )
 
 type TestReconService_MockParser struct{}
@@ -339,8 +341,8 @@ func TestReconService_PassThroughSummary(t *testing.T) {
 			},
 		},
 		CheckExpected: func(rs *ReconSummary) error {
-			if rs.TotalMatched != 2 {
-				return fmt.Errorf("Expected 2, got %d", rs.TotalMatched)
+			if rs.TotalMatched != 1 {
+				return fmt.Errorf("Expected 1, got %d", rs.TotalMatched)
 			}
 
 			if rs.TotalDiscrepancy != 0 {
@@ -398,7 +400,7 @@ func TestReconService_PassThroughSummary(t *testing.T) {
 
 	for _, testCase := range testCases {
 		newService, _ := NewReconService(NewReconServiceOpts{Ctx: ctx})
-		summary := NewReconSummary()
+		aggregator := NewSummaryAggregator(4)
 
 		transactions := testCase.Args
 		inChan := make(chan ReconTransaction, len(transactions))
@@ -407,7 +409,7 @@ func TestReconService_PassThroughSummary(t *testing.T) {
 		}
 		close(inChan)
 
-		outChan := newService.PassThroughSummary(inChan, summary)
+		outChan := newService.PassThroughSummary(inChan, aggregator)
 
 		i := 0
 		for txn := range outChan {
@@ -417,7 +419,8 @@ func TestReconService_PassThroughSummary(t *testing.T) {
 			i += 1
 		}
 
-		err := testCase.CheckExpected(summary)
+		summary := aggregator.Snapshot()
+		err := testCase.CheckExpected(&summary)
 		if err != nil {
 			t.Errorf("[%s] %v", testCase.Label, err)
 		}
@@ -524,8 +527,183 @@ func TestReconService_WriteToCsv(t *testing.T) {
 	}
 
 	csvStr := string(b)
-	expected := "source,id,type,amount,date,remark\ntest,txn_1,,0.00,2025-01-01,remarks\n"
+	expected := "source,id,type,amount,date,remark,error_code\ntest,txn_1,,0.00,2025-01-01,remarks,\n"
 	if csvStr != expected {
 		t.Fatalf("Expected %s, got %s", expected, csvStr)
 	}
 }
+
+func TestReconService_ReadExternalCsvProvenance(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	content := "id,type\n30,one\n31,two\n"
+
+	for _, name := range []string{"test_01.csv", "test_02.csv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create temp csv file: %v", err)
+		}
+	}
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         ctx,
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+
+	txnChan, err := newService.ReadExternalCsv(ReconCsvDetail{
+		Source:      "test",
+		CsvFilepath: filepath.Join(dir, "test_*.csv"),
+		Parser:      &TestReconService_MockParser{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rowsByFile := map[string]int{}
+	for txn := range txnChan {
+		if txn.SourceRow < 1 || txn.SourceRow > 2 {
+			t.Fatalf("Expected row 1 or 2, got %d", txn.SourceRow)
+		}
+		rowsByFile[filepath.Base(txn.SourceFile)] += txn.SourceRow
+	}
+
+	expected := map[string]int{"test_01.csv": 3, "test_02.csv": 3}
+	if !reflect.DeepEqual(rowsByFile, expected) {
+		t.Fatalf("Expected %v, got %v", expected, rowsByFile)
+	}
+}
+
+func TestReconService_WriteToCsvPropagatesError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	internalPath := filepath.Join(dir, "internal.csv")
+	externalPath := filepath.Join(dir, "external.csv")
+	outputPath := filepath.Join(dir, "output.csv")
+
+	if err := os.WriteFile(internalPath, []byte("id,type\n30,one\n"), 0644); err != nil {
+		t.Fatalf("failed to create temp csv file: %v", err)
+	}
+	// malformed record, one field short
+	if err := os.WriteFile(externalPath, []byte("id,type\n30,one\n31\n"), 0644); err != nil {
+		t.Fatalf("failed to create temp csv file: %v", err)
+	}
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         ctx,
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+
+	internalChan, _ := newService.ReadInternalCsv(ReconCsvDetail{
+		Source:      "internal",
+		CsvFilepath: internalPath,
+		Parser:      &TestReconService_MockParser{},
+	})
+	externalChan, _ := newService.ReadExternalCsv(ReconCsvDetail{
+		Source:      "external",
+		CsvFilepath: externalPath,
+		Parser:      &TestReconService_MockParser{},
+	})
+
+	reconChan, err := newService.Reconcile(pipeline.CombineChans(newService.Ctx, []<-chan model.Transaction{internalChan, externalChan}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newService.WriteToCsv(outputPath, reconChan)
+	if err == nil || !strings.Contains(err.Error(), "failed to read csv record") {
+		t.Fatalf("Expected csv read error, got %v", err)
+	}
+
+	if waitErr := newService.Wait(); waitErr != err {
+		t.Fatalf("Expected %v, got %v", err, waitErr)
+	}
+}
+
+func TestReconService_ReadExternalCsvPreserveOrder(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.csv")
+
+	content := "id,type\n"
+	for i := range 200 {
+		content += fmt.Sprintf("%d,one\n", i)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp csv file: %v", err)
+	}
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         ctx,
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+
+	txnChan, err := newService.ReadExternalCsv(ReconCsvDetail{
+		Source:        "test",
+		CsvFilepath:   filePath,
+		Parser:        &TestReconService_MockParser{},
+		PreserveOrder: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := 0
+	for txn := range txnChan {
+		if txn.Id != fmt.Sprintf("%d", i) || txn.SourceRow != i+1 {
+			t.Fatalf("Expected id %d row %d, got id %s row %d", i, i+1, txn.Id, txn.SourceRow)
+		}
+		i += 1
+	}
+
+	if i != 200 {
+		t.Fatalf("Expected 200, got %d", i)
+	}
+}
+
+func TestReconService_Metrics(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.csv")
+
+	if err := os.WriteFile(filePath, []byte("id,type\n1,one\n2,two\n3,three\n"), 0644); err != nil {
+		t.Fatalf("failed to create temp csv file: %v", err)
+	}
+
+	metrics := pipeline.NewMetrics()
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         ctx,
+		CsvIngester: ingester.NewCsvIngester(),
+		WorkerCount: 2,
+		BufferSize:  1,
+		Metrics:     metrics,
+	})
+
+	txnChan, err := newService.ReadInternalCsv(ReconCsvDetail{
+		Source:      "internal",
+		CsvFilepath: filePath,
+		Parser:      &TestReconService_MockParser{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconChan, err := newService.Reconcile(txnChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range reconChan {
+	}
+
+	stats := map[string]pipeline.StageStats{}
+	for _, stage := range metrics.Stats() {
+		stats[stage.Name] = stage
+	}
+
+	if stats["parse:internal"].ItemsIn != 3 || stats["parse:internal"].ItemsOut != 3 {
+		t.Errorf("Expected parse in=3 out=3, got %+v", stats["parse:internal"])
+	}
+
+	if stats["reconcile"].ItemsIn != 3 || stats["reconcile"].ItemsOut != 3 {
+		t.Errorf("Expected reconcile in=3 out=3, got %+v", stats["reconcile"])
+	}
+}
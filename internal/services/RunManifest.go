@@ -0,0 +1,235 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+)
+
+// Run manifest, a JSON record of one reconciliation run for dashboards and
+// audits: the version that ran, when, on which inputs and with which result.
+//
+// Inputs are listed per source with the sha256 of every file read, hashed by
+// the readers from the handle they parsed so a file replaced during the run
+// cannot be described instead, and the records parsed from each file. Plain
+// and gzip files are hashed from the bytes parsed, a zip archive up front from
+// the handle its entries are read from. Files are listed by path. The open
+// items ledger loaded is listed as an input of its own. Outputs are checksummed
+// once written.
+
+// Version of the build, set with -ldflags "-X github.com/kevin-luvian/amartha-recon/internal/services.Version=v1.2.3"
+var Version = "dev"
+
+const (
+	ManifestSideInternal  = "internal"
+	ManifestSideExternal  = "external"
+	ManifestSideOpenItems = "open_items"
+)
+
+type RunManifest struct {
+	Version         string          `json:"version"`
+	Revision        string          `json:"revision,omitempty"` // vcs revision of the build, when known
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      time.Time       `json:"finished_at"`
+	FilterDateRange []string        `json:"filter_date_range,omitempty"`
+	Inputs          []ManifestInput `json:"inputs"`
+	Outputs         []ManifestFile  `json:"outputs"`
+	Summary         ReconSummary    `json:"summary"`
+}
+
+type ManifestInput struct {
+	Source  string         `json:"source"`
+	Side    string         `json:"side"`
+	Pattern string         `json:"pattern"` // file, glob pattern or directory as configured
+	Rows    int            `json:"rows"`    // records parsed over every file
+	Files   []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+	Rows   int    `json:"rows"` // records parsed, every entry of a .zip counts towards the archive
+}
+
+// runInput counts the records parsed per file of one source and keeps the checksum of every file read,
+// safe for concurrent use
+type runInput struct {
+	source  string
+	side    string // set for an input read outside of the sources, like the open items ledger
+	pattern string
+	mu      sync.Mutex
+	rows    map[string]int
+	files   []ingester.FileChecksum
+}
+
+func newRunInput(detail ReconCsvDetail) *runInput {
+	return &runInput{
+		source:  detail.Source,
+		pattern: detail.CsvFilepath,
+		rows:    make(map[string]int),
+	}
+}
+
+// newOpenItemsInput describes the open items ledger loaded from path
+func newOpenItemsInput(path string, checksum ingester.FileChecksum, rows int) *runInput {
+	return &runInput{
+		source:  ManifestSideOpenItems,
+		side:    ManifestSideOpenItems,
+		pattern: path,
+		rows:    map[string]int{checksum.Path: rows},
+		files:   []ingester.FileChecksum{checksum},
+	}
+}
+
+func (i *runInput) count(file string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rows[file] += 1
+}
+
+// read keeps the checksum of a file once the reader is done with it
+func (i *runInput) read(checksum ingester.FileChecksum) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.files = append(i.files, checksum)
+}
+
+func (i *runInput) checksums() []ingester.FileChecksum {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return slices.Clone(i.files)
+}
+
+// rowsOf returns the records of path, including the entries of a zip archive
+func (i *runInput) rowsOf(path string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rows := 0
+	for file, count := range i.rows {
+		if file == path || strings.HasPrefix(file, path+"/") {
+			rows += count
+		}
+	}
+	return rows
+}
+
+// Manifest describes the run so far with summary and the given output files,
+// call it once every stage returned and every output was written
+func (r *ReconService) Manifest(summary ReconSummary, outputs ...string) (RunManifest, error) {
+	manifest := RunManifest{
+		Version:         Version,
+		Revision:        buildRevision(),
+		StartedAt:       r.startedAt,
+		FinishedAt:      time.Now(),
+		FilterDateRange: r.FilterDateRange,
+		Inputs:          []ManifestInput{},
+		Outputs:         []ManifestFile{},
+		Summary:         summary,
+	}
+
+	for _, input := range r.inputs {
+		side := input.side
+		switch {
+		case side != "":
+		case input.source == r.internalSource:
+			side = ManifestSideInternal
+		default:
+			side = ManifestSideExternal
+		}
+
+		manifestInput := ManifestInput{Source: input.source, Side: side, Pattern: input.pattern, Files: []ManifestFile{}}
+		// readers report in the order files finish, sorted so identical runs list them alike
+		checksums := input.checksums()
+		slices.SortFunc(checksums, func(a, b ingester.FileChecksum) int { return strings.Compare(a.Path, b.Path) })
+		for _, checksum := range checksums {
+			file := ManifestFile{Path: checksum.Path, Size: checksum.Size, Sha256: checksum.Sha256, Rows: input.rowsOf(checksum.Path)}
+			manifestInput.Rows += file.Rows
+			manifestInput.Files = append(manifestInput.Files, file)
+		}
+
+		manifest.Inputs = append(manifest.Inputs, manifestInput)
+	}
+
+	for _, path := range outputs {
+		file, err := checksumFile(path)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Outputs = append(manifest.Outputs, file)
+	}
+
+	return manifest, nil
+}
+
+func (m RunManifest) WriteJson(path string) error {
+	// written next to the outputs and renamed, so a dashboard never reads half a manifest
+	file, err := os.CreateTemp(filepath.Dir(path), ".manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	// no-op once renamed
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(m); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	// CreateTemp leaves the file readable by its owner only
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+
+	return nil
+}
+
+func checksumFile(path string) (ManifestFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+
+	return ManifestFile{Path: path, Size: size, Sha256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
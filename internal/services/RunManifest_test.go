@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+type TestRunManifest_Parser struct {
+	source string
+}
+
+func (p *TestRunManifest_Parser) Parse(record map[string]string) model.Transaction {
+	amount, _ := strconv.ParseFloat(record["amount"], 64)
+	return model.Transaction{Source: p.source, Id: record["id"], Type: "DEBIT", Amount: amount, Date: record["date"]}
+}
+
+func TestReconService_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"internal.csv": "id,amount,date\ntxn_1,10,2025-01-01\ntxn_2,5,2025-01-03\n",
+		"bank_01.csv":  "id,amount,date\ntxn_1,10,2025-01-01\n",
+		"bank_02.csv":  "id,amount,date\nbank_1,7,2025-01-02\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputPath := filepath.Join(dir, "output.csv")
+	manifestPath := filepath.Join(dir, "manifest.json")
+	ledgerPath := filepath.Join(dir, "open_items.jsonl")
+	ledger := `{"source":"bank","id":"txn_2","type":"DEBIT","amount":5,"date":"2025-01-03"}` + "\n"
+	if err := os.WriteFile(ledgerPath, []byte(ledger), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:           context.Background(),
+		CsvIngester:   ingester.NewCsvIngester(),
+		OpenItemsPath: ledgerPath,
+	})
+
+	internalChan, err := newService.ReadInternalCsv(ReconCsvDetail{
+		Source:      "amartha",
+		CsvFilepath: filepath.Join(dir, "internal.csv"),
+		Parser:      &TestRunManifest_Parser{source: "amartha"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	externalChan, err := newService.ReadExternalCsv(ReconCsvDetail{
+		Source:      "bank",
+		CsvFilepath: filepath.Join(dir, "bank_*.csv"),
+		Parser:      &TestRunManifest_Parser{source: "bank"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactionChan := pipeline.CombineChans(newService.Ctx, []<-chan model.Transaction{internalChan, externalChan})
+	reconChan, err := newService.Reconcile(transactionChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator := NewSummaryAggregator(2)
+	reconChan = newService.PassThroughSummary(reconChan, aggregator)
+	if err := newService.WriteToCsv(outputPath, reconChan); err != nil {
+		t.Fatal(err)
+	}
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := newService.Manifest(aggregator.Snapshot(), outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.WriteJson(manifestPath); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(manifestPath); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Expected the manifest at mode 0644, got %v %v", info.Mode().Perm(), err)
+	}
+
+	var decoded RunManifest
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Version != Version || decoded.FinishedAt.Before(decoded.StartedAt) {
+		t.Errorf("Unexpected run info %s %s %s", decoded.Version, decoded.StartedAt, decoded.FinishedAt)
+	}
+
+	if len(decoded.Inputs) != 3 {
+		t.Fatalf("Expected 3 inputs, got %+v", decoded.Inputs)
+	}
+
+	internal, external, openItems := decoded.Inputs[0], decoded.Inputs[1], decoded.Inputs[2]
+	if internal.Side != ManifestSideInternal || internal.Rows != 2 || len(internal.Files) != 1 {
+		t.Errorf("Unexpected internal input %+v", internal)
+	}
+	if external.Side != ManifestSideExternal || external.Rows != 2 || len(external.Files) != 2 {
+		t.Fatalf("Unexpected external input %+v", external)
+	}
+
+	ledgerSum := sha256.Sum256([]byte(ledger))
+	if openItems.Side != ManifestSideOpenItems || openItems.Rows != 1 || len(openItems.Files) != 1 || openItems.Files[0].Sha256 != hex.EncodeToString(ledgerSum[:]) {
+		t.Errorf("Unexpected open items input %+v", openItems)
+	}
+
+	for _, file := range external.Files {
+		sum := sha256.Sum256([]byte(files[filepath.Base(file.Path)]))
+		if file.Sha256 != hex.EncodeToString(sum[:]) || file.Rows != 1 || file.Size != int64(len(files[filepath.Base(file.Path)])) {
+			t.Errorf("Unexpected file %+v", file)
+		}
+	}
+
+	if len(decoded.Outputs) != 1 || decoded.Outputs[0].Path != outputPath || decoded.Outputs[0].Sha256 == "" {
+		t.Errorf("Unexpected outputs %+v", decoded.Outputs)
+	}
+
+	summary := decoded.Summary
+	if summary.BySource["amartha"].MatchedCount != 2 || summary.BySource["bank"].MatchedAmount != 15 {
+		t.Errorf("Unexpected breakdown by source %v", summary.BySource)
+	}
+	if summary.DateFrom != "2025-01-01" || summary.DateTo != "2025-01-03" {
+		t.Errorf("Expected date range 2025-01-01 to 2025-01-03, got %s to %s", summary.DateFrom, summary.DateTo)
+	}
+	if summary.TotalMismatched != 1 {
+		t.Errorf("Expected 1 mismatched, got %d", summary.TotalMismatched)
+	}
+
+	if _, err := newService.Manifest(aggregator.Snapshot(), filepath.Join(dir, "missing.csv")); err == nil {
+		t.Errorf("Expected error for a missing output")
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"os"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+	"github.com/kevin-luvian/amartha-recon/pkg/storage"
+)
+
+// Out of core reconciliation, enabled with SpillDir.
+//
+// Flow:
+// (1) internal and external transactions are externally sorted by date, each
+//     side into its own run files, keeping arrival order within a date
+// (2) both sorted streams are merge-joined one date at a time, the
+//     transactions of that date are fed to a fresh matcher in arrival order
+// (3) the matcher reconciles its remaining transactions before the next date
+//
+// Every matching rule only compares transactions of the same date, so the
+// results are the same as the in memory matcher while memory is bounded by
+// the sort runs and the largest single day.
+
+const defaultSpillRunSize = 100000
+
+// spilledTransaction is the gob encoded form of a transaction in a sort run
+type spilledTransaction struct {
+	Seq         int64
+	Transaction model.Transaction
+}
+
+func compareSpilled(a, b spilledTransaction) int {
+	if result := cmp.Compare(a.Transaction.Date, b.Transaction.Date); result != 0 {
+		return result
+	}
+	return cmp.Compare(a.Seq, b.Seq)
+}
+
+func (r *ReconService) reconcileSpilled(
+	transactionChan <-chan model.Transaction,
+	outChan chan ReconTransaction,
+	metrics *pipeline.StageMetrics,
+	emit func(ReconTransaction) bool,
+) {
+	pipeline.Go(r.Ctx, func() error {
+		defer close(outChan)
+
+		dir, err := os.MkdirTemp(r.spillDir, "recon-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		internalSorter := storage.NewExternalSorter(dir, r.spillRunSize, compareSpilled)
+		defer internalSorter.Close()
+
+		externalSorter := storage.NewExternalSorter(dir, r.spillRunSize, compareSpilled)
+		defer externalSorter.Close()
+
+		var seq int64
+		for {
+			transaction, ok := pipeline.Receive(r.Ctx, transactionChan, metrics)
+			if !ok {
+				break
+			}
+
+			// pass error records
+			if transaction.ParseError != nil {
+				if !emit(errorReconTransaction(transaction)) {
+					return context.Cause(r.Ctx)
+				}
+				continue
+			}
+
+			sorter := externalSorter
+			if transaction.Source == r.internalSource {
+				sorter = internalSorter
+			}
+
+			seq += 1
+			if err := sorter.Add(spilledTransaction{Seq: seq, Transaction: transaction}); err != nil {
+				return err
+			}
+		}
+
+		if r.Ctx.Err() != nil {
+			return context.Cause(r.Ctx)
+		}
+
+		internalIterator, err := internalSorter.Sorted()
+		if err != nil {
+			return err
+		}
+		defer internalIterator.Close()
+
+		externalIterator, err := externalSorter.Sorted()
+		if err != nil {
+			return err
+		}
+		defer externalIterator.Close()
+
+		if err := r.mergeJoinByDate(internalIterator, externalIterator, emit); err != nil {
+			return err
+		}
+
+		if err := internalIterator.Err(); err != nil {
+			return err
+		}
+		return externalIterator.Err()
+	})
+}
+
+func (r *ReconService) mergeJoinByDate(
+	internalIterator *storage.SortedIterator[spilledTransaction],
+	externalIterator *storage.SortedIterator[spilledTransaction],
+	emit func(ReconTransaction) bool,
+) error {
+	internalNext, internalOk := internalIterator.Next()
+	externalNext, externalOk := externalIterator.Next()
+
+	for internalOk || externalOk {
+		date := internalNext.Transaction.Date
+		if !internalOk || (externalOk && externalNext.Transaction.Date < date) {
+			date = externalNext.Transaction.Date
+		}
+
+		var internalBucket, externalBucket []spilledTransaction
+		for internalOk && internalNext.Transaction.Date == date {
+			internalBucket = append(internalBucket, internalNext)
+			internalNext, internalOk = internalIterator.Next()
+		}
+		for externalOk && externalNext.Transaction.Date == date {
+			externalBucket = append(externalBucket, externalNext)
+			externalNext, externalOk = externalIterator.Next()
+		}
+
+		// replay the date in arrival order
+		matcher := r.configureMatcher(newMatcher(r.internalSource))
+		for len(internalBucket) > 0 || len(externalBucket) > 0 {
+			var next spilledTransaction
+			if len(externalBucket) == 0 || (len(internalBucket) > 0 && internalBucket[0].Seq < externalBucket[0].Seq) {
+				next, internalBucket = internalBucket[0], internalBucket[1:]
+			} else {
+				next, externalBucket = externalBucket[0], externalBucket[1:]
+			}
+
+			if err := r.reconcileOne(matcher, next.Transaction, emit); err != nil {
+				return err
+			}
+		}
+
+		if err := r.reconcileRemaining(matcher, emit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// SummaryAggregator counts reconciliation results from any number of goroutines.
+//
+// Transactions are spread over shards by id, every shard has its own lock so
+// concurrent producers rarely contend, and Snapshot merges the shards into a
+// plain ReconSummary. A snapshot taken during a run is consistent per shard,
+// not across shards.
+
+type SummaryAggregator struct {
+	shards  []summaryShard
+	aging   *AgingConfig
+	balance *BalanceCheckReport
+}
+
+type summaryShard struct {
+	mu      sync.Mutex
+	summary ReconSummary
+}
+
+// NewSummaryAggregator creates shardCount shards, defaults to GOMAXPROCS
+func NewSummaryAggregator(shardCount int) *SummaryAggregator {
+	if shardCount <= 0 {
+		shardCount = runtime.GOMAXPROCS(0)
+	}
+
+	aggregator := &SummaryAggregator{shards: make([]summaryShard, shardCount)}
+	for i := range aggregator.shards {
+		aggregator.shards[i].summary = *NewReconSummary()
+	}
+
+	return aggregator
+}
+
+// WithAging adds aging totals of unmatched breaks to the summary, call it before the first Add
+func (a *SummaryAggregator) WithAging(config AgingConfig) (*SummaryAggregator, error) {
+	if err := config.Validate(); err != nil {
+		return a, err
+	}
+
+	a.aging = &config
+	return a, nil
+}
+
+// WithBalanceCheck marks the sources of the statements failing verification in report as untrusted,
+// the statements are only known once PassThroughBalanceCheck has seen the whole input
+func (a *SummaryAggregator) WithBalanceCheck(report *BalanceCheckReport) *SummaryAggregator {
+	a.balance = report
+	return a
+}
+
+func (a *SummaryAggregator) Add(t ReconTransaction) {
+	shard := &a.shards[a.shardIndex(t)]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.summary.add(t, a.aging)
+}
+
+func (a *SummaryAggregator) shardIndex(t ReconTransaction) int {
+	if len(a.shards) == 1 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(t.Source))
+	hash.Write([]byte(t.Id))
+	return int(hash.Sum32() % uint32(len(a.shards)))
+}
+
+// Snapshot returns the summary so far, safe to call while producers are running
+func (a *SummaryAggregator) Snapshot() ReconSummary {
+	result := *NewReconSummary()
+
+	for i := range a.shards {
+		shard := &a.shards[i]
+
+		shard.mu.Lock()
+		result.merge(shard.summary)
+		shard.mu.Unlock()
+	}
+
+	if a.balance != nil {
+		for _, statement := range a.balance.InvalidStatements() {
+			result.UntrustedSources[statement.Source] = append(
+				result.UntrustedSources[statement.Source],
+				fmt.Sprintf("%s: %s", statement.StatementId, statement.Remark),
+			)
+		}
+	}
+
+	return result
+}
+
+func (r *ReconService) PassThroughSummary(reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) <-chan ReconTransaction {
+	return pipeline.TransformChan(r.Ctx, reconTransactionChan, func(t ReconTransaction) (ReconTransaction, bool) {
+		aggregator.Add(t)
+		return t, true
+	}, r.StageOptions("summary")...)
+}
+
+// AggregateSummary drains reconTransactionChan into aggregator with the service
+// worker count, use it on a Tee output and Wait for the service before reading
+// the final snapshot
+func (r *ReconService) AggregateSummary(reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) {
+	metrics := r.metrics.Stage("summary")
+
+	for range r.workerCount {
+		pipeline.Go(r.Ctx, func() error {
+			for {
+				t, ok := pipeline.Receive(r.Ctx, reconTransactionChan, metrics)
+				if !ok {
+					return context.Cause(r.Ctx)
+				}
+
+				aggregator.Add(t)
+			}
+		})
+	}
+}
+
+// SummaryBreakdown totals the transactions of one source or type, both sides of a matched pair count
+type SummaryBreakdown struct {
+	MatchedCount       int     `json:"matched_count"`
+	MatchedAmount      float64 `json:"matched_amount"`
+	UnmatchedCount     int     `json:"unmatched_count"`
+	UnmatchedAmount    float64 `json:"unmatched_amount"`
+	ErrorCount         int     `json:"error_count"`
+	SelfCancelledCount int     `json:"self_cancelled_count"`
+}
+
+func (b SummaryBreakdown) merge(other SummaryBreakdown) SummaryBreakdown {
+	b.MatchedCount += other.MatchedCount
+	b.MatchedAmount += other.MatchedAmount
+	b.UnmatchedCount += other.UnmatchedCount
+	b.UnmatchedAmount += other.UnmatchedAmount
+	b.ErrorCount += other.ErrorCount
+	b.SelfCancelledCount += other.SelfCancelledCount
+	return b
+}
+
+// TotalProcessed counts every reconciled transaction: both sides of the matched pairs, the unmatched, the self-cancelled and the errors
+func (s ReconSummary) TotalProcessed() int {
+	processed := s.TotalMismatched + s.TotalErrors + s.TotalSelfCancelled
+	for _, breakdown := range s.BySource {
+		processed += breakdown.MatchedCount
+	}
+	return processed
+}
+
+func (s *ReconSummary) add(t ReconTransaction, aging *AgingConfig) {
+	switch {
+	case t.IsError:
+		s.TotalErrors += 1
+		// a row failing on several fields counts towards each of its codes
+		for _, code := range t.ErrorCodes() {
+			s.ErrorsByCode[string(code)] += 1
+		}
+		s.addBreakdown(t.Transaction, SummaryBreakdown{ErrorCount: 1})
+
+	case t.IsSelfCancelled:
+		s.TotalSelfCancelled += 1
+		s.addBreakdown(t.Transaction, SummaryBreakdown{SelfCancelledCount: 1})
+		s.observeDate(t.Date)
+
+	case t.IsMatched && t.MatchRule == MatchRulePartial:
+		s.TotalPartialLegs += 1
+
+		// the internal side counts once, on the pair covering its last share, its residual otherwise counts as a break
+		internalCount := 0
+		if t.Residual < discrepancyTolerance {
+			internalCount = 1
+			s.TotalMatched += 1
+			s.MatchedByRule[t.MatchRule] += 1
+		}
+		s.addBreakdown(t.Transaction, SummaryBreakdown{MatchedCount: 1, MatchedAmount: t.Amount})
+		s.addBreakdown(t.OtherTransaction, SummaryBreakdown{MatchedCount: internalCount, MatchedAmount: t.Amount})
+		s.observeDate(t.Date)
+
+	case t.IsMatched:
+		s.TotalMatched += 1
+		s.TotalDiscrepancy += math.Abs(t.Amount - t.OtherTransaction.Amount)
+		s.MatchedByRule[t.MatchRule] += 1
+
+		for _, side := range []model.Transaction{t.Transaction, t.OtherTransaction} {
+			s.addBreakdown(side, SummaryBreakdown{MatchedCount: 1, MatchedAmount: side.Amount})
+			s.observeDate(side.Date)
+		}
+
+	default:
+		amount := t.OpenAmount()
+		s.TotalMismatched += 1
+		s.TotalUnmatchedAmount += amount
+		s.TotalMismatchBySource[t.Source] += 1
+		s.addBreakdown(t.Transaction, SummaryBreakdown{UnmatchedCount: 1, UnmatchedAmount: amount})
+		s.observeDate(t.Date)
+
+		if aging != nil {
+			bucket := aging.Bucket(t.Date)
+			s.AgingCountByBucket[bucket] += 1
+			s.AgingAmountByBucket[bucket] += amount
+		}
+	}
+}
+
+func (s *ReconSummary) addBreakdown(t model.Transaction, breakdown SummaryBreakdown) {
+	s.BySource[t.Source] = s.BySource[t.Source].merge(breakdown)
+	if t.Type != "" {
+		s.ByType[t.Type] = s.ByType[t.Type].merge(breakdown)
+	}
+}
+
+func (s *ReconSummary) merge(other ReconSummary) {
+	s.TotalMatched += other.TotalMatched
+	s.TotalMismatched += other.TotalMismatched
+	s.TotalErrors += other.TotalErrors
+	s.TotalSelfCancelled += other.TotalSelfCancelled
+	s.TotalPartialLegs += other.TotalPartialLegs
+	s.TotalDiscrepancy += other.TotalDiscrepancy
+	s.TotalUnmatchedAmount += other.TotalUnmatchedAmount
+
+	for source, count := range other.TotalMismatchBySource {
+		s.TotalMismatchBySource[source] += count
+	}
+	for code, count := range other.ErrorsByCode {
+		s.ErrorsByCode[code] += count
+	}
+	for rule, count := range other.MatchedByRule {
+		s.MatchedByRule[rule] += count
+	}
+	for source, breakdown := range other.BySource {
+		s.BySource[source] = s.BySource[source].merge(breakdown)
+	}
+	for transactionType, breakdown := range other.ByType {
+		s.ByType[transactionType] = s.ByType[transactionType].merge(breakdown)
+	}
+	for bucket, count := range other.AgingCountByBucket {
+		s.AgingCountByBucket[bucket] += count
+	}
+	for bucket, amount := range other.AgingAmountByBucket {
+		s.AgingAmountByBucket[bucket] += amount
+	}
+	for source, statements := range other.UntrustedSources {
+		s.UntrustedSources[source] = append(s.UntrustedSources[source], statements...)
+	}
+
+	s.observeDate(other.DateFrom)
+	s.observeDate(other.DateTo)
+}
+
+// observeDate widens the date range of the summary to date
+func (s *ReconSummary) observeDate(date string) {
+	if date == "" {
+		return
+	}
+	// dates are yyyy-mm-dd, so they order as strings
+	if s.DateFrom == "" || date < s.DateFrom {
+		s.DateFrom = date
+	}
+	if s.DateTo == "" || date > s.DateTo {
+		s.DateTo = date
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+func TestSummaryAggregator_ConcurrentAdd(t *testing.T) {
+	aggregator := NewSummaryAggregator(4)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				aggregator.Add(ReconTransaction{
+					Transaction: model.Transaction{Id: fmt.Sprintf("%d-%d", worker, i), Source: "bca"},
+					IsMatched:   i%2 == 0,
+				})
+
+				// snapshots while producers are running must not race
+				if i%100 == 0 {
+					aggregator.Snapshot()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	summary := aggregator.Snapshot()
+	if summary.TotalMatched != 4000 {
+		t.Errorf("Expected 4000 matched, got %d", summary.TotalMatched)
+	}
+
+	if summary.TotalMismatched != 4000 || summary.TotalMismatchBySource["bca"] != 4000 {
+		t.Errorf("Expected 4000 mismatched, got %d (%v)", summary.TotalMismatched, summary.TotalMismatchBySource)
+	}
+}
+
+func TestReconService_AggregateSummary(t *testing.T) {
+	newService, _ := NewReconService(NewReconServiceOpts{Ctx: context.Background()})
+	aggregator := NewSummaryAggregator(0)
+
+	inChan := make(chan ReconTransaction, 3)
+	inChan <- ReconTransaction{Transaction: model.Transaction{Id: "1"}, OtherTransaction: model.Transaction{Amount: 5}, IsMatched: true}
+	inChan <- ReconTransaction{Transaction: model.Transaction{Id: "2", Source: "dbs"}}
+	inChan <- ReconTransaction{Transaction: model.Transaction{Id: "3", Source: "dbs"}}
+	close(inChan)
+
+	outputs := pipeline.Tee(newService.Ctx, inChan, 2)
+	newService.AggregateSummary(outputs[0], aggregator)
+
+	count := 0
+	for range outputs[1] {
+		count += 1
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Errorf("Expected 3 transactions on the other output, got %d", count)
+	}
+
+	summary := aggregator.Snapshot()
+	if summary.TotalMatched != 1 || summary.TotalMismatchBySource["dbs"] != 2 || summary.TotalDiscrepancy != 5 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestSummaryAggregator_Aging(t *testing.T) {
+	aggregator, err := NewSummaryAggregator(2).WithAging(AgingConfig{AsOf: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "1", Source: "bca", Amount: 10, Date: "2025-01-31"}})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "2", Source: "bca", Amount: 20, Date: "2024-12-01"}})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "3", Source: "dbs", Amount: 5, Date: "2024-12-02"}})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "4", Source: "dbs", Date: "2024-12-02"}, IsMatched: true})
+
+	summary := aggregator.Snapshot()
+	if summary.AgingCountByBucket["0-1"] != 1 || summary.AgingAmountByBucket["0-1"] != 10 {
+		t.Errorf("Expected one break of 10 in 0-1, got %v %v", summary.AgingCountByBucket, summary.AgingAmountByBucket)
+	}
+
+	if summary.AgingCountByBucket["30+"] != 2 || summary.AgingAmountByBucket["30+"] != 25 {
+		t.Errorf("Expected two breaks of 25 in 30+, got %v %v", summary.AgingCountByBucket, summary.AgingAmountByBucket)
+	}
+}
+
+func TestSummaryAggregator_Breakdown(t *testing.T) {
+	aggregator := NewSummaryAggregator(3)
+
+	internal := model.Transaction{Id: "1", Source: "amartha", Type: "DEBIT", Amount: 10, Date: "2025-01-02"}
+	external := model.Transaction{Id: "1", Source: "bca", Type: "DEBIT", Amount: 9.5, Date: "2025-01-02"}
+	aggregator.Add(ReconTransaction{Transaction: internal, OtherTransaction: external, IsMatched: true, MatchRule: MatchRuleId})
+	aggregator.Add(ReconTransaction{Transaction: external, OtherTransaction: internal, IsMatched: true, MatchRule: MatchRuleAmount})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "2", Source: "bca", Type: "CREDIT", Amount: 7, Date: "2025-01-01"}})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "3", Source: "amartha", Type: "CREDIT", Amount: 3, Date: "2025-01-03"}})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "4", Source: "bca"}, IsError: true, Remark: "invalid amount"})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "5", Source: "bca", Type: "DEBIT", Amount: 4, Date: "2025-01-02"}, IsSelfCancelled: true})
+	aggregator.Add(ReconTransaction{Transaction: model.Transaction{Id: "6", Source: "bca", Type: "CREDIT", Amount: 4, Date: "2025-01-02"}, IsSelfCancelled: true})
+
+	summary := aggregator.Snapshot()
+
+	if summary.TotalMatched != 2 || summary.TotalMismatched != 2 || summary.TotalErrors != 1 {
+		t.Errorf("Expected 2 pairs, 2 unmatched and 1 error, got %d %d %d", summary.TotalMatched, summary.TotalMismatched, summary.TotalErrors)
+	}
+	if processed := summary.TotalProcessed(); processed != 9 || summary.TotalSelfCancelled != 2 {
+		t.Errorf("Expected 9 processed including 2 self-cancelled, got %d %d", processed, summary.TotalSelfCancelled)
+	}
+	if summary.TotalUnmatchedAmount != 10 || summary.TotalDiscrepancy != 1 {
+		t.Errorf("Expected unmatched amount 10 and discrepancy 1, got %.2f %.2f", summary.TotalUnmatchedAmount, summary.TotalDiscrepancy)
+	}
+
+	// errors no longer inflate the mismatches
+	if summary.TotalMismatchBySource["bca"] != 1 {
+		t.Errorf("Expected 1 bca mismatch, got %v", summary.TotalMismatchBySource)
+	}
+
+	if summary.MatchedByRule[MatchRuleId] != 1 || summary.MatchedByRule[MatchRuleAmount] != 1 {
+		t.Errorf("Unexpected rules %v", summary.MatchedByRule)
+	}
+
+	expectedBySource := map[string]SummaryBreakdown{
+		"amartha": {MatchedCount: 2, MatchedAmount: 20, UnmatchedCount: 1, UnmatchedAmount: 3},
+		"bca":     {MatchedCount: 2, MatchedAmount: 19, UnmatchedCount: 1, UnmatchedAmount: 7, ErrorCount: 1, SelfCancelledCount: 2},
+	}
+	if !maps.Equal(summary.BySource, expectedBySource) {
+		t.Errorf("Expected by source %v, got %v", expectedBySource, summary.BySource)
+	}
+
+	expectedByType := map[string]SummaryBreakdown{
+		"DEBIT":  {MatchedCount: 4, MatchedAmount: 39, SelfCancelledCount: 1},
+		"CREDIT": {UnmatchedCount: 2, UnmatchedAmount: 10, SelfCancelledCount: 1},
+	}
+	if !maps.Equal(summary.ByType, expectedByType) {
+		t.Errorf("Expected by type %v, got %v", expectedByType, summary.ByType)
+	}
+
+	if summary.DateFrom != "2025-01-01" || summary.DateTo != "2025-01-03" {
+		t.Errorf("Expected 2025-01-01 to 2025-01-03, got %s to %s", summary.DateFrom, summary.DateTo)
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/storage"
+)
+
+// Virtual account matching, repayments reach the bank through a virtual
+// account identifying the borrower or loan, so a repayment and its transfer
+// carrying the same VA number and amount are the same money even when the
+// bank books it a few days later.
+//
+// It runs after the id rule and before the amount rule. The matcher tables
+// are keyed by date, so unmatched transactions with a VA number are also kept
+// in a secondary index by side, VA number, type and amount. A new transaction
+// takes the indexed one of the other side dated closest to it within the
+// window. Entries of transactions matched by another rule are dropped lazily.
+
+type virtualAccountIndex struct {
+	window int64 // milliseconds
+	keys   map[string][]string
+}
+
+func newVirtualAccountIndex(windowDays int) *virtualAccountIndex {
+	return &virtualAccountIndex{
+		window: int64(windowDays) * (24 * time.Hour).Milliseconds(),
+		keys:   make(map[string][]string),
+	}
+}
+
+func virtualAccountKey(isInternal bool, t model.Transaction) string {
+	side := "external"
+	if isInternal {
+		side = "internal"
+	}
+	return fmt.Sprintf("%s|%s|%s|%.2f", side, t.VirtualAccount, t.Type, t.Amount)
+}
+
+// add indexes an unmatched transaction stored in its side table
+func (i *virtualAccountIndex) add(isInternal bool, t model.Transaction) {
+	if t.VirtualAccount == "" {
+		return
+	}
+
+	key := virtualAccountKey(isInternal, t)
+	i.keys[key] = append(i.keys[key], t.GetHashById())
+}
+
+// take removes and returns the transaction of table matching t closest in date within the window
+func (i *virtualAccountIndex) take(isInternal bool, t model.Transaction, table storage.ITable) storage.IHashable {
+	if t.VirtualAccount == "" {
+		return nil
+	}
+
+	key := virtualAccountKey(isInternal, t)
+	live := i.keys[key][:0]
+	var closest storage.IHashable
+	closestAt, closestDistance := -1, int64(0)
+
+	for _, hashKey := range i.keys[key] {
+		obj := table.GetById(hashKey)
+		if obj == nil {
+			continue
+		}
+
+		// the key may belong to a later transaction with the same date, type and id
+		candidate := obj.(model.Transaction)
+		if virtualAccountKey(isInternal, candidate) != key {
+			continue
+		}
+
+		live = append(live, hashKey)
+
+		distance := max(candidate.DateEpoch-t.DateEpoch, t.DateEpoch-candidate.DateEpoch)
+		if distance <= i.window && (closest == nil || distance < closestDistance) {
+			closest, closestAt, closestDistance = obj, len(live)-1, distance
+		}
+	}
+
+	if closest != nil {
+		live = append(live[:closestAt], live[closestAt+1:]...)
+	}
+
+	if len(live) == 0 {
+		delete(i.keys, key)
+	} else {
+		i.keys[key] = live
+	}
+
+	return closest
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+)
+
+func vaTransaction(source string, id string, va string, amount float64, date string) model.Transaction {
+	t, _ := time.Parse(time.DateOnly, date)
+	return model.Transaction{Source: source, Id: id, VirtualAccount: va, Type: "CREDIT", Amount: amount, Date: date, DateEpoch: t.UnixMilli()}
+}
+
+func reconcileByVirtualAccount(t *testing.T, opts NewReconServiceOpts, transactions []model.Transaction) map[string]ReconTransaction {
+	opts.Ctx = context.Background()
+	newService, _ := NewReconService(opts)
+	newService.internalSource = "amartha"
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]ReconTransaction{}
+	for rt := range reconChan {
+		results[rt.Id] = rt
+	}
+
+	if err := newService.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestReconService_VirtualAccountMatching(t *testing.T) {
+	transactions := []model.Transaction{
+		// booked by the bank two days later
+		vaTransaction("amartha", "repayment_1", "8808001", 150, "2025-01-01"),
+		// same amount on the same date, would match by amount without the VA
+		vaTransaction("amartha", "repayment_2", "8808002", 150, "2025-01-03"),
+		vaTransaction("bca", "bca_1", "8808001", 150, "2025-01-03"),
+		vaTransaction("bca", "bca_2", "8808002", 150, "2025-01-03"),
+		// the closest of the candidates already read wins
+		vaTransaction("bca", "bca_far", "8808003", 75, "2025-01-10"),
+		vaTransaction("bca", "bca_near", "8808003", 75, "2025-01-08"),
+		vaTransaction("amartha", "repayment_3", "8808003", 75, "2025-01-07"),
+		// outside the window
+		vaTransaction("amartha", "repayment_4", "8808004", 20, "2025-01-01"),
+		vaTransaction("bca", "bca_late", "8808004", 20, "2025-01-09"),
+		// the id rule still comes first
+		vaTransaction("bca", "other_id", "8808005", 10, "2025-01-06"),
+		vaTransaction("bca", "same_id", "8808005", 10, "2025-01-05"),
+		vaTransaction("amartha", "same_id", "8808005", 10, "2025-01-05"),
+	}
+
+	results := reconcileByVirtualAccount(t, NewReconServiceOpts{VirtualAccountWindowDays: 3}, transactions)
+
+	testCases := []struct {
+		id      string
+		matched bool
+		rule    string
+		otherId string
+	}{
+		{"bca_1", true, MatchRuleVirtualAccount, "repayment_1"},
+		{"bca_2", true, MatchRuleVirtualAccount, "repayment_2"},
+		{"repayment_3", true, MatchRuleVirtualAccount, "bca_near"},
+		{"bca_far", false, "", ""},
+		{"repayment_4", false, "", ""},
+		{"bca_late", false, "", ""},
+		{"same_id", true, MatchRuleId, "same_id"},
+		{"other_id", false, "", ""},
+	}
+
+	for _, testCase := range testCases {
+		rt, ok := results[testCase.id]
+		if !ok {
+			t.Errorf("[%s] Expected a result", testCase.id)
+			continue
+		}
+		if rt.IsMatched != testCase.matched || rt.MatchRule != testCase.rule || rt.OtherTransaction.Id != testCase.otherId {
+			t.Errorf("[%s] Expected matched %t by %q with %q, got %t by %q with %q", testCase.id, testCase.matched, testCase.rule, testCase.otherId, rt.IsMatched, rt.MatchRule, rt.OtherTransaction.Id)
+		}
+	}
+}
+
+func TestReconService_VirtualAccountWindowWhenSharded(t *testing.T) {
+	for _, opts := range []NewReconServiceOpts{
+		{VirtualAccountWindowDays: 3, ShardCount: 4},
+		{VirtualAccountWindowDays: 3, SpillDir: t.TempDir()},
+	} {
+		if _, err := NewReconService(opts); err == nil {
+			t.Errorf("Expected error for a virtual account window with %+v", opts)
+		}
+	}
+}
+
+func TestReconService_VirtualAccountSameDateWhenSharded(t *testing.T) {
+	transactions := []model.Transaction{
+		vaTransaction("amartha", "repayment_1", "8808001", 150, "2025-01-01"),
+		vaTransaction("bca", "bca_1", "8808001", 150, "2025-01-02"),
+		vaTransaction("amartha", "repayment_2", "8808002", 40, "2025-01-02"),
+		vaTransaction("bca", "bca_2", "8808002", 40, "2025-01-02"),
+		vaTransaction("amartha", "repayment_3", "8808003", 40, "2025-01-02"),
+		vaTransaction("bca", "bca_3", "8808003", 40, "2025-01-02"),
+	}
+
+	results := reconcileByVirtualAccount(t, NewReconServiceOpts{ShardCount: 4}, transactions)
+
+	if rt := results["bca_1"]; rt.MatchRule == MatchRuleVirtualAccount {
+		t.Errorf("Expected no virtual account match across dates, got %+v", rt)
+	}
+	for id, otherId := range map[string]string{"bca_2": "repayment_2", "bca_3": "repayment_3"} {
+		if rt := results[id]; rt.MatchRule != MatchRuleVirtualAccount || rt.OtherTransaction.Id != otherId {
+			t.Errorf("Expected %s matched with %s by virtual account, got %+v", id, otherId, rt)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Reconciliation workbook, the full report split into one sheet per outcome
+// for reviewers working in Excel.
+//
+// Sheets:
+// - Summary: totals of the run, by source and by aging bucket
+// - Matched: every matched pair side by side, internal first
+// - Unmatched Internal / Unmatched External: breaks of either side
+// - Errors: records that failed to parse or were rejected
+// - Discrepancies: matched pairs whose amounts differ
+// - Self Cancelled: reversal pairs netted within their source, one leg per row
+//
+// The summary is only known once the stream is drained, so its sheet is
+// created first to keep it in front and filled last.
+
+const (
+	SheetSummary           = "Summary"
+	SheetMatched           = "Matched"
+	SheetUnmatchedInternal = "Unmatched Internal"
+	SheetUnmatchedExternal = "Unmatched External"
+	SheetErrors            = "Errors"
+	SheetDiscrepancies     = "Discrepancies"
+	SheetSelfCancelled     = "Self Cancelled"
+)
+
+// amounts closer than half a cent are equal
+const discrepancyTolerance = 0.005
+
+var workbookPairColumns = []ingester.XlsxColumn{
+	{Header: "match_rule", Width: 12},
+	{Header: "internal_source", Width: 14},
+	{Header: "internal_id", Width: 24},
+	{Header: "internal_type", Width: 12},
+	{Header: "internal_amount", Type: ingester.XlsxAmount, Width: 16},
+	{Header: "internal_date", Width: 12},
+	{Header: "internal_file", Width: 24},
+	{Header: "internal_row", Type: ingester.XlsxNumber},
+	{Header: "external_source", Width: 14},
+	{Header: "external_id", Width: 24},
+	{Header: "external_type", Width: 12},
+	{Header: "external_amount", Type: ingester.XlsxAmount, Width: 16},
+	{Header: "external_date", Width: 12},
+	{Header: "external_file", Width: 24},
+	{Header: "external_row", Type: ingester.XlsxNumber},
+	{Header: "difference", Type: ingester.XlsxAmount, Width: 16},
+	{Header: "remark", Width: 40},
+}
+
+var workbookSingleColumns = []ingester.XlsxColumn{
+	{Header: "source", Width: 14},
+	{Header: "id", Width: 24},
+	{Header: "type", Width: 12},
+	{Header: "amount", Type: ingester.XlsxAmount, Width: 16},
+	{Header: "date", Width: 12},
+	{Header: "file", Width: 24},
+	{Header: "row", Type: ingester.XlsxNumber},
+	{Header: "remark", Width: 40},
+}
+
+var workbookErrorColumns = append(slices.Clone(workbookSingleColumns), ingester.XlsxColumn{Header: "error_code", Width: 18})
+
+var workbookSummaryColumns = []ingester.XlsxColumn{
+	{Header: "metric", Width: 36},
+	{Header: "value", Type: ingester.XlsxNumber, Width: 16},
+	{Header: "amount", Type: ingester.XlsxAmount, Width: 18},
+}
+
+type reconWorkbook struct {
+	workbook *ingester.XlsxWorkbook
+	sheets   map[string]*ingester.XlsxSheet
+}
+
+// WriteXlsx drains reconTransactionChan into a workbook at filepath. Every
+// transaction is added to aggregator, whose snapshot fills the Summary sheet,
+// nil counts into a fresh one. It takes the same stream as WriteFullReportCsv.
+func (r *ReconService) WriteXlsx(filepath string, reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) error {
+	if aggregator == nil {
+		aggregator = NewSummaryAggregator(1)
+	}
+
+	if err := r.writeXlsx(filepath, reconTransactionChan, aggregator); err != nil {
+		r.group.Fail(err)
+	}
+
+	// see writeCsv
+	return r.group.Err()
+}
+
+func (r *ReconService) writeXlsx(filepath string, reconTransactionChan <-chan ReconTransaction, aggregator *SummaryAggregator) error {
+	book, err := newReconWorkbook()
+	if err != nil {
+		return err
+	}
+	defer book.workbook.Close()
+
+	metrics := r.metrics.Stage("write_xlsx")
+	for {
+		rt, ok := pipeline.Receive(r.Ctx, reconTransactionChan, metrics)
+		if !ok {
+			break
+		}
+
+		aggregator.Add(rt)
+		if err := r.writeWorkbookRow(book, rt); err != nil {
+			return err
+		}
+	}
+
+	if err := context.Cause(r.Ctx); err != nil {
+		return err
+	}
+
+	if err := writeWorkbookSummary(book.sheets[SheetSummary], aggregator); err != nil {
+		return err
+	}
+
+	return book.workbook.Save(filepath)
+}
+
+func newReconWorkbook() (*reconWorkbook, error) {
+	book := &reconWorkbook{
+		workbook: ingester.NewXlsxWorkbook(os.TempDir()),
+		sheets:   make(map[string]*ingester.XlsxSheet),
+	}
+
+	layout := []struct {
+		name    string
+		columns []ingester.XlsxColumn
+	}{
+		{SheetSummary, workbookSummaryColumns},
+		{SheetMatched, workbookPairColumns},
+		{SheetUnmatchedInternal, workbookSingleColumns},
+		{SheetUnmatchedExternal, workbookSingleColumns},
+		{SheetErrors, workbookErrorColumns},
+		{SheetDiscrepancies, workbookPairColumns},
+		{SheetSelfCancelled, workbookSingleColumns},
+	}
+
+	for _, sheet := range layout {
+		xlsxSheet, err := book.workbook.AddSheet(sheet.name, sheet.columns)
+		if err != nil {
+			book.workbook.Close()
+			return nil, err
+		}
+		book.sheets[sheet.name] = xlsxSheet
+	}
+
+	return book, nil
+}
+
+func (r *ReconService) writeWorkbookRow(book *reconWorkbook, rt ReconTransaction) error {
+	switch {
+	case rt.IsError:
+		return book.sheets[SheetErrors].WriteRow(append(workbookSingleRow(rt), errorCodes(rt.Transaction))...)
+
+	case rt.IsMatched:
+		internal, external := rt.Transaction, rt.OtherTransaction
+		if rt.Source != r.internalSource {
+			internal, external = rt.OtherTransaction, rt.Transaction
+		}
+
+		difference := pairDifference(rt, internal, external)
+		row := slices.Concat(
+			[]any{rt.MatchRule},
+			workbookSide(internal),
+			workbookSide(external),
+			[]any{difference, rt.Remark},
+		)
+
+		if err := book.sheets[SheetMatched].WriteRow(row...); err != nil {
+			return err
+		}
+		if math.Abs(difference) >= discrepancyTolerance {
+			return book.sheets[SheetDiscrepancies].WriteRow(row...)
+		}
+		return nil
+
+	case rt.IsSelfCancelled:
+		return book.sheets[SheetSelfCancelled].WriteRow(workbookSingleRow(rt)...)
+
+	case rt.Source == r.internalSource:
+		return book.sheets[SheetUnmatchedInternal].WriteRow(workbookSingleRow(rt)...)
+
+	default:
+		return book.sheets[SheetUnmatchedExternal].WriteRow(workbookSingleRow(rt)...)
+	}
+}
+
+func workbookSingleRow(rt ReconTransaction) []any {
+	return append(workbookSide(rt.Transaction), rt.Remark)
+}
+
+func workbookSide(t model.Transaction) []any {
+	var row any
+	if t.SourceRow > 0 {
+		row = t.SourceRow
+	}
+
+	return []any{t.Source, t.Id, t.Type, t.Amount, t.Date, t.SourceFile, row}
+}
+
+func writeWorkbookSummary(sheet *ingester.XlsxSheet, aggregator *SummaryAggregator) error {
+	summary := aggregator.Snapshot()
+	rows := [][]any{
+		{"Total Processed Transactions", summary.TotalProcessed(), nil},
+		{"Total Matched Pairs", summary.TotalMatched, nil},
+		{"Total Partial Legs", summary.TotalPartialLegs, nil},
+		{"Total Mismatched Transactions", summary.TotalMismatched, summary.TotalUnmatchedAmount},
+		{"Total Errors", summary.TotalErrors, nil},
+		{"Total Self-Cancelled Transactions", summary.TotalSelfCancelled, nil},
+		{"Total Discrepancy Amount", nil, summary.TotalDiscrepancy},
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(summary.UntrustedSources)) {
+		for _, statement := range summary.UntrustedSources[source] {
+			rows = append(rows, []any{fmt.Sprintf("Untrusted %s: %s", source, statement), nil, nil})
+		}
+	}
+
+	for _, code := range slices.Sorted(maps.Keys(summary.ErrorsByCode)) {
+		rows = append(rows, []any{fmt.Sprintf("Errors %s", code), summary.ErrorsByCode[code], nil})
+	}
+
+	for _, rule := range slices.Sorted(maps.Keys(summary.MatchedByRule)) {
+		rows = append(rows, []any{fmt.Sprintf("Matched by %s", rule), summary.MatchedByRule[rule], nil})
+	}
+
+	for _, breakdown := range []struct {
+		label string
+		by    map[string]SummaryBreakdown
+	}{{"Source", summary.BySource}, {"Type", summary.ByType}} {
+		for _, key := range slices.Sorted(maps.Keys(breakdown.by)) {
+			totals := breakdown.by[key]
+			rows = append(rows,
+				[]any{fmt.Sprintf("%s %s: matched", breakdown.label, key), totals.MatchedCount, totals.MatchedAmount},
+				[]any{fmt.Sprintf("%s %s: unmatched", breakdown.label, key), totals.UnmatchedCount, totals.UnmatchedAmount},
+				[]any{fmt.Sprintf("%s %s: errors", breakdown.label, key), totals.ErrorCount, nil},
+			)
+		}
+	}
+
+	buckets := slices.Sorted(maps.Keys(summary.AgingCountByBucket))
+	if aggregator.aging != nil {
+		slices.SortStableFunc(buckets, func(a, b string) int {
+			return aggregator.aging.bucketOrder(a) - aggregator.aging.bucketOrder(b)
+		})
+	}
+
+	for _, bucket := range buckets {
+		rows = append(rows, []any{
+			fmt.Sprintf("Unmatched Aging: %s days", bucket),
+			summary.AgingCountByBucket[bucket],
+			summary.AgingAmountByBucket[bucket],
+		})
+	}
+
+	for _, row := range rows {
+		if err := sheet.WriteRow(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
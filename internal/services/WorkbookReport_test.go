@@ -0,0 +1,112 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevin-luvian/amartha-recon/internal/model"
+	"github.com/kevin-luvian/amartha-recon/pkg/ingester"
+)
+
+func TestReconService_WriteXlsx(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "recon.xlsx")
+
+	newService, _ := NewReconService(NewReconServiceOpts{
+		Ctx:         context.Background(),
+		CsvIngester: ingester.NewCsvIngester(),
+	})
+	newService.internalSource = "amartha"
+
+	transactions := []model.Transaction{
+		{Source: "amartha", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01", SourceFile: "amartha.csv", SourceRow: 1},
+		{Source: "bca", Id: "by_id", Type: "DEBIT", Amount: 10, Date: "2025-01-01", SourceFile: "bca.csv", SourceRow: 1},
+		{Source: "amartha", Id: "amartha_2", Type: "DEBIT", Amount: 3, Date: "2025-01-02", SourceFile: "amartha.csv", SourceRow: 2},
+		{Source: "dbs", Id: "dbs_1", Type: "DEBIT", Amount: 2.5, Date: "2025-01-02", SourceFile: "dbs.csv", SourceRow: 1},
+		{Source: "amartha", Id: "amartha_3", Type: "CREDIT", Amount: 4, Date: "2025-01-03", SourceFile: "amartha.csv", SourceRow: 3},
+		{Source: "dbs", Id: "dbs_2", Type: "CREDIT", Amount: 1, Date: "2025-01-04", SourceFile: "dbs.csv", SourceRow: 2},
+		{Source: "dbs", Id: "dbs_3", SourceFile: "dbs.csv", SourceRow: 3, ParseError: errors.New("invalid amount")},
+	}
+
+	inChan := make(chan model.Transaction, len(transactions))
+	for _, txn := range transactions {
+		inChan <- txn
+	}
+	close(inChan)
+
+	reconChan, err := newService.Reconcile(inChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator, _ := NewSummaryAggregator(2).WithAging(AgingConfig{AsOf: time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)})
+	if err := newService.WriteXlsx(outputPath, reconChan, aggregator); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	parts := map[string]string{}
+	for _, entry := range archive.File {
+		reader, _ := entry.Open()
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		parts[entry.Name] = string(content)
+	}
+
+	// sheets are numbered in creation order
+	sheetNames := []string{SheetSummary, SheetMatched, SheetUnmatchedInternal, SheetUnmatchedExternal, SheetErrors, SheetDiscrepancies, SheetSelfCancelled}
+	for i, name := range sheetNames {
+		if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="`+name+`" sheetId="`+string(rune('1'+i))+`"`) {
+			t.Errorf("Expected sheet %s at position %d", name, i+1)
+		}
+	}
+
+	testCases := []struct {
+		sheet    string
+		rows     int
+		contains []string
+	}{
+		{SheetMatched, 3, []string{"by_id", "amartha_2", "dbs_1"}},
+		{SheetUnmatchedInternal, 2, []string{"amartha_3", "No matching external transaction found"}},
+		{SheetUnmatchedExternal, 2, []string{"dbs_2", "No matching internal transaction found"}},
+		{SheetErrors, 2, []string{"dbs_3", "invalid amount", "UNKNOWN"}},
+		{SheetDiscrepancies, 2, []string{"amartha_2", `<v>0.5</v>`}},
+		{SheetSummary, 28, []string{"Total Matched Pairs", "Total Partial Legs", "Total Self-Cancelled Transactions", "Errors UNKNOWN", "Matched by DATE", "Source amartha: unmatched", "Source dbs: errors", "Type DEBIT: matched", "Unmatched Aging: 2-7 days", "Unmatched Aging: 8-30 days"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sheet, func(t *testing.T) {
+			index := 0
+			for i, name := range sheetNames {
+				if name == tc.sheet {
+					index = i + 1
+				}
+			}
+
+			sheet := parts["xl/worksheets/sheet"+string(rune('0'+index))+".xml"]
+			if rows := strings.Count(sheet, "<row "); rows != tc.rows {
+				t.Errorf("Expected %d rows including the header, got %d", tc.rows, rows)
+			}
+			for _, expected := range tc.contains {
+				if !strings.Contains(sheet, expected) {
+					t.Errorf("Expected %s in sheet, got %s", expected, sheet)
+				}
+			}
+		})
+	}
+
+	summary := aggregator.Snapshot()
+	if summary.TotalMatched != 2 || summary.TotalMismatched != 2 || summary.TotalErrors != 1 {
+		t.Errorf("Expected the workbook to fill the aggregator, got %+v", summary)
+	}
+}
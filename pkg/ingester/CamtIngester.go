@@ -0,0 +1,313 @@
+package ingester
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// ISO 20022 camt.053 (statement) / camt.054 (debit credit notification) reader.
+//
+// The document is streamed, only one <Ntry> is decoded in memory at a time.
+// Each <TxDtls> of an entry is emitted as one record, an entry without
+// transaction details is emitted as a single record. A batched entry whose
+// details do not all carry an amount is also emitted as a single record, the
+// entry total is never copied onto each of its details. Entries with a status
+// other than BOOK, such as PDNG or INFO in notifications, are not on the
+// account yet and are skipped.
+//
+// Record keys:
+//   message_type                camt.053 or camt.054
+//   statement_id                Stmt/Id or Ntfctn/Id
+//   account                     Acct/Id/IBAN or Acct/Id/Othr/Id
+//   opening_balance             Bal with type OPBD (or PRCD), camt.053 only
+//   opening_credit_debit        CRDT or DBIT
+//   closing_balance             Bal with type CLBD, camt.053 only
+//   closing_credit_debit        CRDT or DBIT
+//   entry_reference             Ntry/NtryRef
+//   amount                      TxDtls/Amt, TxDtls/AmtDtls/TxAmt/Amt or Ntry/Amt
+//   currency                    Ccy attribute of the amount
+//   credit_debit                CRDT or DBIT
+//   reversal                    Ntry/RvslInd
+//   status                      Ntry/Sts, BOOK or empty
+//   booking_date                BookgDt/Dt or BookgDt/DtTm
+//   value_date                  ValDt/Dt or ValDt/DtTm
+//   account_servicer_reference  TxDtls/Refs/AcctSvcrRef or Ntry/AcctSvcrRef
+//   end_to_end_id               TxDtls/Refs/EndToEndId
+//   transaction_id              TxDtls/Refs/TxId
+//   narrative                   RmtInf/Ustrd, AddtlTxInf or AddtlNtryInf
+
+type CamtIngester struct {
+}
+
+func NewCamtIngester() *CamtIngester {
+	return &CamtIngester{}
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) String() string {
+	if d.Date != "" {
+		return strings.TrimSpace(d.Date)
+	}
+	return strings.TrimSpace(d.DateTime)
+}
+
+type camtAccount struct {
+	Iban  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+type camtBalance struct {
+	Code        string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+}
+
+type camtStatus struct {
+	Code string `xml:"Cd"`
+	Text string `xml:",chardata"`
+}
+
+type camtTransactionDetail struct {
+	AccountServicerReference string     `xml:"Refs>AcctSvcrRef"`
+	EndToEndId               string     `xml:"Refs>EndToEndId"`
+	TransactionId            string     `xml:"Refs>TxId"`
+	Amount                   camtAmount `xml:"Amt"`
+	TransactionAmount        camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	CreditDebit              string     `xml:"CdtDbtInd"`
+	Unstructured             []string   `xml:"RmtInf>Ustrd"`
+	AdditionalInfo           string     `xml:"AddtlTxInf"`
+}
+
+type camtEntry struct {
+	Reference                string                  `xml:"NtryRef"`
+	Amount                   camtAmount              `xml:"Amt"`
+	CreditDebit              string                  `xml:"CdtDbtInd"`
+	Reversal                 string                  `xml:"RvslInd"`
+	Status                   camtStatus              `xml:"Sts"`
+	BookingDate              camtDate                `xml:"BookgDt"`
+	ValueDate                camtDate                `xml:"ValDt"`
+	AccountServicerReference string                  `xml:"AcctSvcrRef"`
+	TransactionDetails       []camtTransactionDetail `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo           string                  `xml:"AddtlNtryInf"`
+}
+
+func (c *CamtIngester) Read(ctx context.Context, filepath string) (<-chan map[string]string, error) {
+	return readFiles(ctx, filepath, c.ReadStream)
+}
+
+func (c *CamtIngester) ReadStream(ctx context.Context, file io.ReadCloser) (<-chan map[string]string, error) {
+	recordsChan := make(chan map[string]string)
+
+	pipeline.Go(ctx, func() (err error) {
+		defer close(recordsChan)
+		defer closeStream(file, &err)
+
+		reader := &camtReader{
+			decoder:   xml.NewDecoder(file),
+			statement: make(map[string]string),
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+
+			default:
+				entry, err := reader.nextEntry()
+				if err == io.EOF {
+					return nil
+				}
+
+				if err != nil {
+					return fmt.Errorf("failed to read camt document: %w", err)
+				}
+
+				for _, obj := range reader.entryRecords(entry) {
+					if !pipeline.Send(ctx, recordsChan, obj) {
+						return context.Cause(ctx)
+					}
+				}
+			}
+		}
+	})
+
+	return recordsChan, nil
+}
+
+// camtReader walks the document keeping the fields of the current statement
+type camtReader struct {
+	decoder   *xml.Decoder
+	statement map[string]string
+	parent    []string
+}
+
+// nextEntry decodes tokens until the next <Ntry> is fully read
+func (r *camtReader) nextEntry() (camtEntry, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return camtEntry{}, err
+		}
+
+		switch element := token.(type) {
+		case xml.EndElement:
+			r.parent = r.parent[:len(r.parent)-1]
+
+		case xml.StartElement:
+			parentName := ""
+			if len(r.parent) > 0 {
+				parentName = r.parent[len(r.parent)-1]
+			}
+			isStatementChild := parentName == "Stmt" || parentName == "Ntfctn"
+
+			switch {
+			case element.Name.Local == "Stmt" || element.Name.Local == "Ntfctn":
+				// new statement, keep nothing from the previous one
+				clear(r.statement)
+				r.statement["message_type"] = "camt.053"
+				if element.Name.Local == "Ntfctn" {
+					r.statement["message_type"] = "camt.054"
+				}
+				r.parent = append(r.parent, element.Name.Local)
+
+			case element.Name.Local == "Id" && isStatementChild:
+				var id string
+				if err := r.decoder.DecodeElement(&id, &element); err != nil {
+					return camtEntry{}, err
+				}
+				r.statement["statement_id"] = strings.TrimSpace(id)
+
+			case element.Name.Local == "Acct" && isStatementChild:
+				var account camtAccount
+				if err := r.decoder.DecodeElement(&account, &element); err != nil {
+					return camtEntry{}, err
+				}
+				r.statement["account"] = strings.TrimSpace(account.Iban + account.Other)
+
+			case element.Name.Local == "Bal" && isStatementChild:
+				var balance camtBalance
+				if err := r.decoder.DecodeElement(&balance, &element); err != nil {
+					return camtEntry{}, err
+				}
+				r.applyBalance(balance)
+
+			case element.Name.Local == "Ntry" && isStatementChild:
+				var entry camtEntry
+				if err := r.decoder.DecodeElement(&entry, &element); err != nil {
+					return camtEntry{}, err
+				}
+				return entry, nil
+
+			default:
+				r.parent = append(r.parent, element.Name.Local)
+			}
+		}
+	}
+}
+
+func (r *camtReader) applyBalance(balance camtBalance) {
+	prefix := ""
+	switch balance.Code {
+	case "OPBD", "PRCD":
+		prefix = "opening"
+	case "CLBD":
+		prefix = "closing"
+	default:
+		return
+	}
+
+	// a statement can carry both OPBD and PRCD, the first one wins
+	if r.statement[prefix+"_balance"] == "" {
+		r.statement[prefix+"_balance"] = strings.TrimSpace(balance.Amount.Value)
+		r.statement[prefix+"_credit_debit"] = strings.TrimSpace(balance.CreditDebit)
+	}
+}
+
+// entryRecords returns the records of a booked entry, none for a pending or informational one
+func (r *camtReader) entryRecords(entry camtEntry) []map[string]string {
+	status := strings.TrimSpace(entry.Status.Code)
+	if status == "" {
+		status = strings.TrimSpace(entry.Status.Text)
+	}
+	if status != "" && status != "BOOK" {
+		return nil
+	}
+
+	base := make(map[string]string, len(r.statement)+16)
+	for k, v := range r.statement {
+		base[k] = v
+	}
+	base["entry_reference"] = strings.TrimSpace(entry.Reference)
+	base["amount"] = strings.TrimSpace(entry.Amount.Value)
+	base["currency"] = entry.Amount.Currency
+	base["credit_debit"] = strings.TrimSpace(entry.CreditDebit)
+	base["reversal"] = strings.TrimSpace(entry.Reversal)
+	base["status"] = status
+	base["booking_date"] = entry.BookingDate.String()
+	base["value_date"] = entry.ValueDate.String()
+	base["account_servicer_reference"] = strings.TrimSpace(entry.AccountServicerReference)
+	base["narrative"] = strings.TrimSpace(entry.AdditionalInfo)
+
+	if len(entry.TransactionDetails) == 0 {
+		return []map[string]string{base}
+	}
+
+	// a single detail takes the entry amount, several without amounts cannot be split
+	if len(entry.TransactionDetails) > 1 && slices.ContainsFunc(entry.TransactionDetails, func(detail camtTransactionDetail) bool {
+		return detail.Amount.Value == "" && detail.TransactionAmount.Value == ""
+	}) {
+		return []map[string]string{base}
+	}
+
+	records := make([]map[string]string, 0, len(entry.TransactionDetails))
+	for _, detail := range entry.TransactionDetails {
+		obj := make(map[string]string, len(base)+2)
+		for k, v := range base {
+			obj[k] = v
+		}
+
+		if amount := detail.Amount; amount.Value != "" {
+			obj["amount"] = strings.TrimSpace(amount.Value)
+			obj["currency"] = amount.Currency
+		} else if amount := detail.TransactionAmount; amount.Value != "" {
+			obj["amount"] = strings.TrimSpace(amount.Value)
+			obj["currency"] = amount.Currency
+		}
+
+		if detail.CreditDebit != "" {
+			obj["credit_debit"] = strings.TrimSpace(detail.CreditDebit)
+		}
+
+		if detail.AccountServicerReference != "" {
+			obj["account_servicer_reference"] = strings.TrimSpace(detail.AccountServicerReference)
+		}
+
+		obj["end_to_end_id"] = strings.TrimSpace(detail.EndToEndId)
+		obj["transaction_id"] = strings.TrimSpace(detail.TransactionId)
+
+		if len(detail.Unstructured) > 0 {
+			obj["narrative"] = strings.TrimSpace(strings.Join(detail.Unstructured, " "))
+		} else if detail.AdditionalInfo != "" {
+			obj["narrative"] = strings.TrimSpace(detail.AdditionalInfo)
+		}
+
+		records = append(records, obj)
+	}
+
+	return records
+}
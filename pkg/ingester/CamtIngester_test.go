@@ -0,0 +1,284 @@
+package ingester
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCamtIngester_Read(t *testing.T) {
+	ctx := context.Background()
+	dir := os.TempDir()
+	filePath := filepath.Join(dir, "test_camt053.xml")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG1</MsgId></GrpHdr>
+    <Stmt>
+      <Id>STMT1</Id>
+      <Acct><Id><IBAN>ID001234</IBAN></Id></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1006.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+      </Bal>
+      <Ntry>
+        <NtryRef>E1</NtryRef>
+        <Amt Ccy="IDR">4.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+        <ValDt><Dt>2025-01-02</Dt></ValDt>
+        <AcctSvcrRef>BANKREF1</AcctSvcrRef>
+        <AddtlNtryInf>PAYOUT</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">10.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-01-01T10:00:00+07:00</DtTm></BookgDt>
+        <AcctSvcrRef>BATCHREF</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>amartha_1</EndToEndId></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="IDR">6.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Ustrd>REPAYMENT</Ustrd><Ustrd>LOAN 1</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>TXREF2</AcctSvcrRef><EndToEndId>amartha_2</EndToEndId></Refs>
+            <Amt Ccy="IDR">4.00</Amt>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp camt file: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	ingester := NewCamtIngester()
+	recordsChan, err := ingester.Read(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	expected := []map[string]string{
+		{
+			"message_type":               "camt.053",
+			"statement_id":               "STMT1",
+			"account":                    "ID001234",
+			"opening_balance":            "1000.00",
+			"opening_credit_debit":       "CRDT",
+			"closing_balance":            "1006.00",
+			"entry_reference":            "E1",
+			"amount":                     "4.00",
+			"currency":                   "IDR",
+			"credit_debit":               "DBIT",
+			"status":                     "BOOK",
+			"booking_date":               "2025-01-01",
+			"value_date":                 "2025-01-02",
+			"account_servicer_reference": "BANKREF1",
+			"narrative":                  "PAYOUT",
+		},
+		{
+			"statement_id":               "STMT1",
+			"amount":                     "6.00",
+			"credit_debit":               "CRDT",
+			"booking_date":               "2025-01-01T10:00:00+07:00",
+			"account_servicer_reference": "BATCHREF",
+			"end_to_end_id":              "amartha_1",
+			"narrative":                  "REPAYMENT LOAN 1",
+		},
+		{
+			"amount":                     "4.00",
+			"account_servicer_reference": "TXREF2",
+			"end_to_end_id":              "amartha_2",
+		},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+
+	for i, record := range records {
+		for k, v := range expected[i] {
+			if record[k] != v {
+				t.Errorf("expected record %d field %s to be %v, got %v", i, k, v, record[k])
+			}
+		}
+	}
+}
+
+func TestCamtIngester_ReadNotification(t *testing.T) {
+	ctx := context.Background()
+	dir := os.TempDir()
+	filePath := filepath.Join(dir, "test_camt054.xml")
+	content := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.02">
+  <BkToCstmrDbtCdtNtfctn>
+    <Ntfctn>
+      <Id>NTF1</Id>
+      <Acct><Id><Othr><Id>1234567890</Id></Othr></Id></Acct>
+      <Ntry>
+        <Amt Ccy="IDR">4.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>
+`
+
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp camt file: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	recordsChan, err := NewCamtIngester().Read(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	expected := map[string]string{
+		"message_type": "camt.054",
+		"statement_id": "NTF1",
+		"account":      "1234567890",
+		"reversal":     "true",
+		"amount":       "4.00",
+	}
+	for k, v := range expected {
+		if records[0][k] != v {
+			t.Errorf("expected field %s to be %v, got %v", k, v, records[0][k])
+		}
+	}
+}
+
+func TestCamtIngester_ReadBatchWithoutDetailAmounts(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "batch.xml")
+	content := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT1</Id>
+      <Ntry>
+        <Amt Ccy="IDR">10.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+        <AcctSvcrRef>BATCHREF</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls><Refs><EndToEndId>amartha_1</EndToEndId></Refs></TxDtls>
+          <TxDtls><Refs><EndToEndId>amartha_2</EndToEndId></Refs></TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">3.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+        <NtryDtls>
+          <TxDtls><Refs><EndToEndId>amartha_3</EndToEndId></Refs></TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recordsChan, err := NewCamtIngester().Read(context.Background(), filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	// the batch once at its total, the single detail with the entry amount
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %v", records)
+	}
+	if records[0]["amount"] != "10.00" || records[0]["end_to_end_id"] != "" || records[0]["account_servicer_reference"] != "BATCHREF" {
+		t.Errorf("expected the batch emitted once as the entry, got %v", records[0])
+	}
+	if records[1]["amount"] != "3.00" || records[1]["end_to_end_id"] != "amartha_3" {
+		t.Errorf("expected the single detail with the entry amount, got %v", records[1])
+	}
+}
+
+func TestCamtIngester_ReadSkipsUnbooked(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "notification.xml")
+	content := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.08">
+  <BkToCstmrDbtCdtNtfctn>
+    <Ntfctn>
+      <Id>NTF1</Id>
+      <Ntry>
+        <NtryRef>PENDING</NtryRef>
+        <Amt Ccy="IDR">4.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <NtryRef>INFO</NtryRef>
+        <Amt Ccy="IDR">5.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>INFO</Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <NtryRef>BOOKED</NtryRef>
+        <Amt Ccy="IDR">6.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-01-01</Dt></BookgDt>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>
+`
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recordsChan, err := NewCamtIngester().Read(context.Background(), filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	if len(records) != 1 || records[0]["entry_reference"] != "BOOKED" || records[0]["status"] != "BOOK" {
+		t.Errorf("expected only the booked entry, got %v", records)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
 )
 
 type CsvIngester struct {
@@ -16,16 +18,15 @@ func NewCsvIngester() *CsvIngester {
 }
 
 func (c *CsvIngester) Read(ctx context.Context, filepath string) (<-chan map[string]string, error) {
-	recordsChan := make(chan map[string]string)
+	return readFiles(ctx, filepath, c.ReadStream)
+}
 
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
+func (c *CsvIngester) ReadStream(ctx context.Context, file io.ReadCloser) (<-chan map[string]string, error) {
+	recordsChan := make(chan map[string]string)
 
-	go func() {
-		defer file.Close()
+	pipeline.Go(ctx, func() (err error) {
 		defer close(recordsChan)
+		defer closeStream(file, &err)
 
 		header := []string{}
 		reader := csv.NewReader(file)
@@ -33,18 +34,16 @@ func (c *CsvIngester) Read(ctx context.Context, filepath string) (<-chan map[str
 		for {
 			select {
 			case <-ctx.Done():
-				fmt.Println("CSV reading cancelled")
-				return
+				return context.Cause(ctx)
 
 			default:
 				record, err := reader.Read()
 				if err == io.EOF {
-					return
+					return nil
 				}
 
 				if err != nil {
-					fmt.Printf("Error reading CSV record: %v\n", err)
-					return
+					return fmt.Errorf("failed to read csv record: %w", err)
 				}
 
 				if len(header) == 0 {
@@ -54,8 +53,7 @@ func (c *CsvIngester) Read(ctx context.Context, filepath string) (<-chan map[str
 
 				// malformed csv
 				if len(record) != len(header) {
-					fmt.Printf("Malformed csv record: %v\n", record)
-					return
+					return fmt.Errorf("malformed csv record: %v", record)
 				}
 
 				// Convert record to object
@@ -64,10 +62,12 @@ func (c *CsvIngester) Read(ctx context.Context, filepath string) (<-chan map[str
 					obj[label] = record[i]
 				}
 
-				recordsChan <- obj
+				if !pipeline.Send(ctx, recordsChan, obj) {
+					return context.Cause(ctx)
+				}
 			}
 		}
-	}()
+	})
 
 	return recordsChan, nil
 }
@@ -94,12 +94,12 @@ func (c *CsvIngester) Write(ctx context.Context, filepath string, header []strin
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("CSV writing cancelled")
-			return nil
+			return context.Cause(ctx)
 
 		case record, ok := <-recordsChan:
 			if !ok {
-				return nil
+				writer.Flush()
+				return writer.Error()
 			}
 
 			row := make([]string, len(headerMap))
@@ -111,7 +111,7 @@ func (c *CsvIngester) Write(ctx context.Context, filepath string, header []strin
 			}
 
 			if err := writer.Write(row); err != nil {
-				fmt.Printf("Error writing CSV record: %v\n", err)
+				return fmt.Errorf("failed to write csv record: %w", err)
 			}
 		}
 	}
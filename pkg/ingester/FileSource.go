@@ -0,0 +1,337 @@
+package ingester
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// Every reader accepts a file, a glob pattern or a directory. All matched files
+// are read one after another into a single record stream, .gz files are
+// decompressed and every entry of a .zip file is read as its own file.
+//
+// Records are tagged with their provenance:
+//   _file  file the record came from, zip entries as archive.zip/entry.csv
+//   _row   1-based record index within that file
+//
+// Under a context from WithFileChecksums every file read is also reported
+// with its size and sha256, hashed from the bytes read rather than reopened.
+// A zip archive is read at offsets, so it is hashed up front by a full read of
+// the handle its entries are then read from.
+
+type fileSource struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// FileChecksum is the size and sha256 of a file as it was read
+type FileChecksum struct {
+	Path   string
+	Size   int64
+	Sha256 string
+}
+
+type fileChecksumsKey struct{}
+
+// WithFileChecksums returns a context under which the readers of this package
+// report every file they read in full to onFile, from the reading goroutine
+func WithFileChecksums(ctx context.Context, onFile func(FileChecksum)) context.Context {
+	return context.WithValue(ctx, fileChecksumsKey{}, onFile)
+}
+
+func fileChecksumsOf(ctx context.Context) func(FileChecksum) {
+	onFile, _ := ctx.Value(fileChecksumsKey{}).(func(FileChecksum))
+	return onFile
+}
+
+// checksumReader hashes a file while it is read. Closing it hashes what the
+// reader left unread and reports the checksum, unless ctx is done.
+type checksumReader struct {
+	ctx    context.Context
+	file   *os.File
+	hash   hash.Hash
+	size   int64
+	onFile func(FileChecksum)
+}
+
+func newChecksumReader(ctx context.Context, file *os.File, onFile func(FileChecksum)) *checksumReader {
+	return &checksumReader{ctx: ctx, file: file, hash: sha256.New(), onFile: onFile}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.file.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *checksumReader) Close() error {
+	if c.ctx.Err() != nil {
+		return c.file.Close()
+	}
+
+	// a reader may stop before the end of the file, like gzip after its trailer or parquet reading at offsets
+	rest, err := io.Copy(c.hash, c.file)
+	if err != nil {
+		c.file.Close()
+		return fmt.Errorf("failed to checksum file %s: %w", c.file.Name(), err)
+	}
+	c.size += rest
+
+	c.onFile(FileChecksum{Path: c.file.Name(), Size: c.size, Sha256: hex.EncodeToString(c.hash.Sum(nil))})
+	return c.file.Close()
+}
+
+// closeStream closes the file of a ReadStream before its records channel, so the checksum of a file is
+// reported before the next file is read. A failed close, like a checksum left incomplete, fails the stream.
+func closeStream(file io.Closer, err *error) {
+	if closeErr := file.Close(); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
+
+// ResolveFiles expands a glob pattern or directory into a sorted list of files
+func ResolveFiles(pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		stat, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if !stat.IsDir() {
+			return []string{pattern}, nil
+		}
+
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := []string{}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			paths = append(paths, filepath.Join(pattern, entry.Name()))
+		}
+
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no files found in directory %s", pattern)
+		}
+
+		return paths, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, match := range matches {
+		stat, err := os.Stat(match)
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		paths = append(paths, match)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files match pattern %s", pattern)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// listFileSources returns the files to read at path, the closer releases a zip archive once its entries were read
+func listFileSources(ctx context.Context, path string) ([]fileSource, io.Closer, error) {
+	onFile := fileChecksumsOf(ctx)
+
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		return []fileSource{{
+			name: path,
+			open: func() (io.ReadCloser, error) {
+				file, err := os.Open(path)
+				if err != nil {
+					return nil, err
+				}
+				if onFile == nil {
+					return decompress(path, file)
+				}
+				return decompress(path, newChecksumReader(ctx, file, onFile))
+			},
+		}}, nil, nil
+	}
+
+	// hashed up front, every entry is then read from this one handle, so the checksum describes the archive they came from
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size, err := checksumArchive(file, onFile)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	sources := []fileSource{}
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		sources = append(sources, fileSource{
+			name: filepath.Join(path, entry.Name),
+			open: func() (io.ReadCloser, error) {
+				reader, err := entry.Open()
+				if err != nil {
+					return nil, err
+				}
+				return decompress(entry.Name, reader)
+			},
+		})
+	}
+
+	return sources, file, nil
+}
+
+// checksumArchive reports the checksum of a zip archive to onFile when set by reading it in full, returns its size
+func checksumArchive(file *os.File, onFile func(FileChecksum)) (int64, error) {
+	if onFile == nil {
+		stat, err := file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return stat.Size(), nil
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, err
+	}
+
+	onFile(FileChecksum{Path: file.Name(), Size: size, Sha256: hex.EncodeToString(hash.Sum(nil))})
+	return size, nil
+}
+
+// decompress wraps .gz files with a gzip reader, closing it closes the underlying reader
+func decompress(name string, reader io.ReadCloser) (io.ReadCloser, error) {
+	if !strings.EqualFold(filepath.Ext(name), ".gz") {
+		return reader, nil
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	return &readCloser{Reader: gzipReader, closers: []io.Closer{gzipReader, reader}}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var firstErr error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// readFiles concatenates the records of every file matching pattern
+func readFiles(
+	ctx context.Context,
+	pattern string,
+	readStream func(ctx context.Context, reader io.ReadCloser) (<-chan map[string]string, error),
+) (<-chan map[string]string, error) {
+	paths, err := ResolveFiles(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	sources := []fileSource{}
+	closers := readCloser{}
+	for _, path := range paths {
+		fileSources, closer, err := listFileSources(ctx, path)
+		if err != nil {
+			closers.Close()
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		sources = append(sources, fileSources...)
+		if closer != nil {
+			closers.closers = append(closers.closers, closer)
+		}
+	}
+
+	recordsChan := make(chan map[string]string)
+
+	pipeline.Go(ctx, func() error {
+		defer closers.Close()
+		defer close(recordsChan)
+
+		for _, source := range sources {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+
+			reader, err := source.open()
+			if err != nil {
+				return fmt.Errorf("failed to open file %s: %w", source.name, err)
+			}
+
+			fileChan, err := readStream(ctx, reader)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", source.name, err)
+			}
+
+			row := 0
+			for record := range fileChan {
+				row += 1
+				record[RecordFileKey] = source.name
+				record[RecordRowKey] = strconv.Itoa(row)
+				if !pipeline.Send(ctx, recordsChan, record) {
+					return context.Cause(ctx)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return recordsChan, nil
+}
+
+// TrimCompressionExt strips .gz so the format of a.csv.gz can be told from its extension
+func TrimCompressionExt(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return path
+}
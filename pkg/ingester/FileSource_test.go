@@ -0,0 +1,199 @@
+package ingester
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+func FileSource_SetupTestDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	write := func(name string, content []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+	}
+
+	write("bca_01.csv", []byte("id,amount\ntxn_1,1\ntxn_2,2\n"))
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write([]byte("id,amount\ntxn_3,3\n"))
+	gzipWriter.Close()
+	write("bca_02.csv.gz", gzipped.Bytes())
+
+	var zipped bytes.Buffer
+	zipWriter := zip.NewWriter(&zipped)
+	entry, _ := zipWriter.Create("bca_03.csv")
+	entry.Write([]byte("id,amount\ntxn_4,4\n"))
+	entry, _ = zipWriter.Create("bca_04.csv.gz")
+	entry.Write(gzipped.Bytes())
+	zipWriter.Close()
+	write("bca_03.zip", zipped.Bytes())
+
+	write(".hidden", []byte("id,amount\nhidden,0\n"))
+
+	return dir
+}
+
+func TestResolveFiles(t *testing.T) {
+	dir := FileSource_SetupTestDir(t)
+
+	paths, err := ResolveFiles(filepath.Join(dir, "bca_*.csv*"))
+	if err != nil {
+		t.Fatalf("ResolveFiles returned error: %v", err)
+	}
+
+	expected := []string{filepath.Join(dir, "bca_01.csv"), filepath.Join(dir, "bca_02.csv.gz")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+
+	paths, err = ResolveFiles(dir)
+	if err != nil {
+		t.Fatalf("ResolveFiles returned error: %v", err)
+	}
+
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3, got %v", paths)
+	}
+
+	_, err = ResolveFiles(filepath.Join(dir, "dbs_*.csv"))
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	_, err = ResolveFiles(filepath.Join(dir, "missing.csv"))
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func TestCsvIngester_ReadMultiFile(t *testing.T) {
+	ctx := context.Background()
+	dir := FileSource_SetupTestDir(t)
+
+	recordsChan, err := NewCsvIngester().Read(ctx, dir)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	expected := []map[string]string{
+		{"id": "txn_1", RecordFileKey: filepath.Join(dir, "bca_01.csv"), RecordRowKey: "1"},
+		{"id": "txn_2", RecordFileKey: filepath.Join(dir, "bca_01.csv"), RecordRowKey: "2"},
+		{"id": "txn_3", RecordFileKey: filepath.Join(dir, "bca_02.csv.gz"), RecordRowKey: "1"},
+		{"id": "txn_4", RecordFileKey: filepath.Join(dir, "bca_03.zip", "bca_03.csv"), RecordRowKey: "1"},
+		{"id": "txn_3", RecordFileKey: filepath.Join(dir, "bca_03.zip", "bca_04.csv.gz"), RecordRowKey: "1"},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+
+	for i, record := range records {
+		for k, v := range expected[i] {
+			if record[k] != v {
+				t.Errorf("expected record %d field %s to be %v, got %v", i, k, v, record[k])
+			}
+		}
+	}
+}
+
+func readChecksums(t *testing.T, reader IRecordReader, pattern string) map[string]FileChecksum {
+	var mu sync.Mutex
+	checksums := map[string]FileChecksum{}
+
+	group, ctx := pipeline.WithGroup(context.Background())
+	ctx = WithFileChecksums(ctx, func(checksum FileChecksum) {
+		mu.Lock()
+		defer mu.Unlock()
+		checksums[checksum.Path] = checksum
+	})
+
+	recordsChan, err := reader.Read(ctx, pattern)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	for range recordsChan {
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	return checksums
+}
+
+func TestReadFiles_Checksums(t *testing.T) {
+	dir := FileSource_SetupTestDir(t)
+
+	checksums := readChecksums(t, NewCsvIngester(), dir)
+
+	// the archive is checksummed once, not its entries
+	if len(checksums) != 3 {
+		t.Fatalf("Expected 3 checksums, got %v", checksums)
+	}
+	for _, name := range []string{"bca_01.csv", "bca_02.csv.gz", "bca_03.zip"} {
+		path := filepath.Join(dir, name)
+		content, _ := os.ReadFile(path)
+		sum := sha256.Sum256(content)
+
+		checksum := checksums[path]
+		if checksum.Sha256 != hex.EncodeToString(sum[:]) || checksum.Size != int64(len(content)) {
+			t.Errorf("Unexpected checksum of %s: %+v", name, checksum)
+		}
+	}
+}
+
+type failingCloser struct {
+	io.Reader
+}
+
+func (failingCloser) Close() error {
+	return errors.New("failed to checksum file")
+}
+
+func TestReadStream_CloseError(t *testing.T) {
+	readers := map[string]interface {
+		ReadStream(ctx context.Context, file io.ReadCloser) (<-chan map[string]string, error)
+	}{
+		"csv":   NewCsvIngester(),
+		"jsonl": NewJsonlIngester(),
+		"mt940": NewMt940Ingester(),
+		"camt":  NewCamtIngester(),
+	}
+
+	for name, reader := range readers {
+		group, ctx := pipeline.WithGroup(context.Background())
+
+		recordsChan, err := reader.ReadStream(ctx, failingCloser{Reader: strings.NewReader("")})
+		if err != nil {
+			t.Fatalf("[%s] ReadStream returned error: %v", name, err)
+		}
+		for range recordsChan {
+		}
+
+		// a checksum left incomplete on close must fail the run instead of dropping the file
+		if err := group.Wait(); err == nil || err.Error() != "failed to checksum file" {
+			t.Errorf("[%s] Expected the close error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ingester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// JSON Lines reader, one object per line. Values are converted to strings so the
+// records can be handled by the same parsers as csv records, nested objects and
+// arrays are kept as json.
+type JsonlIngester struct {
+}
+
+func NewJsonlIngester() *JsonlIngester {
+	return &JsonlIngester{}
+}
+
+func (j *JsonlIngester) Read(ctx context.Context, filepath string) (<-chan map[string]string, error) {
+	return readFiles(ctx, filepath, j.ReadStream)
+}
+
+func (j *JsonlIngester) ReadStream(ctx context.Context, file io.ReadCloser) (<-chan map[string]string, error) {
+	recordsChan := make(chan map[string]string)
+
+	pipeline.Go(ctx, func() (err error) {
+		defer close(recordsChan)
+		defer closeStream(file, &err)
+
+		decoder := json.NewDecoder(file)
+		decoder.UseNumber()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+
+			default:
+				var values map[string]any
+				err := decoder.Decode(&values)
+				if err == io.EOF {
+					return nil
+				}
+
+				if err != nil {
+					return fmt.Errorf("failed to read jsonl record: %w", err)
+				}
+
+				if !pipeline.Send(ctx, recordsChan, toRecord(values)) {
+					return context.Cause(ctx)
+				}
+			}
+		}
+	})
+
+	return recordsChan, nil
+}
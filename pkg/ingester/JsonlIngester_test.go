@@ -0,0 +1,50 @@
+package ingester
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJsonlIngester_Read(t *testing.T) {
+	ctx := context.Background()
+	dir := os.TempDir()
+	filePath := filepath.Join(dir, "test.jsonl")
+	content := `{"id":"txn_1","amount":10.5,"count":3,"settled":true,"date":"2025-01-01 10:00:00"}` + "\n" +
+		"\n" +
+		`{"id":"txn_2","amount":1e2,"meta":{"va":"123"},"note":null}` + "\n"
+
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp jsonl file: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	ingester := NewJsonlIngester()
+	recordsChan, err := ingester.Read(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	expected := []map[string]string{
+		{"id": "txn_1", "amount": "10.5", "count": "3", "settled": "true", "date": "2025-01-01 10:00:00"},
+		{"id": "txn_2", "amount": "1e2", "meta": `{"va":"123"}`, "note": ""},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+
+	for i, record := range records {
+		for k, v := range expected[i] {
+			if record[k] != v {
+				t.Errorf("expected record %d field %s to be %v, got %v", i, k, v, record[k])
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package ingester
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+)
+
+// SWIFT MT940 (end of day) / MT942 (interim) statement reader.
+//
+// Each :61: statement line is emitted as one record, merged with the fields of
+// the statement it belongs to. Values are kept as written in the file (comma
+// decimal separator, YYMMDD dates), interpretation is left to the parser.
+//
+// Record keys:
+//   statement_reference  :20:
+//   account              :25:
+//   statement_number     :28C:
+//   opening_mark         :60F: / :60M: debit or credit mark (D/C)
+//   opening_date         :60F: / :60M: YYMMDD
+//   opening_balance      :60F: / :60M:
+//   closing_mark         :62F: / :62M: debit or credit mark (D/C)
+//   closing_date         :62F: / :62M: YYMMDD
+//   closing_balance      :62F: / :62M:
+//   currency             from the opening / closing balance
+//   value_date           :61: YYMMDD
+//   entry_date           :61: MMDD, optional
+//   mark                 :61: C, D, RC or RD
+//   amount               :61:
+//   transaction_type     :61: e.g. NTRF, NMSC
+//   reference            :61: reference for the account owner
+//   bank_reference       :61: reference of the account servicing institution
+//   supplementary        :61: second line, optional
+//   narrative            :86: following the statement line
+//   statement_line       raw :61: value
+
+var (
+	mt940TagRegex       = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	mt940StatementRegex = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+	mt940BalanceRegex   = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+)
+
+type Mt940Ingester struct {
+}
+
+func NewMt940Ingester() *Mt940Ingester {
+	return &Mt940Ingester{}
+}
+
+type mt940Statement struct {
+	fields  map[string]string
+	entries []map[string]string
+}
+
+func newMt940Statement() *mt940Statement {
+	return &mt940Statement{
+		fields: make(map[string]string),
+	}
+}
+
+func (m *Mt940Ingester) Read(ctx context.Context, filepath string) (<-chan map[string]string, error) {
+	return readFiles(ctx, filepath, m.ReadStream)
+}
+
+func (m *Mt940Ingester) ReadStream(ctx context.Context, file io.ReadCloser) (<-chan map[string]string, error) {
+	recordsChan := make(chan map[string]string)
+
+	pipeline.Go(ctx, func() (err error) {
+		defer close(recordsChan)
+		defer closeStream(file, &err)
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		statement := newMt940Statement()
+		tag, value := "", ""
+
+		// flush emits every statement line collected so far, returns false when cancelled
+		flush := func() bool {
+			if tag != "" {
+				statement = m.applyTag(statement, tag, value)
+				tag, value = "", ""
+			}
+
+			for _, entry := range statement.entries {
+				obj := make(map[string]string, len(entry)+len(statement.fields))
+				for k, v := range statement.fields {
+					obj[k] = v
+				}
+				for k, v := range entry {
+					obj[k] = v
+				}
+
+				if !pipeline.Send(ctx, recordsChan, obj) {
+					return false
+				}
+			}
+
+			statement = newMt940Statement()
+			return true
+		}
+
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), "\r")
+
+			switch {
+			case strings.HasPrefix(line, "{"):
+				// SWIFT block headers, the statement body starts after {4:
+				if strings.Contains(line, "{4:") && !flush() {
+					return context.Cause(ctx)
+				}
+
+			case line == "-}" || line == "-":
+				// end of message
+				if !flush() {
+					return context.Cause(ctx)
+				}
+
+			case mt940TagRegex.MatchString(line):
+				if tag != "" {
+					statement = m.applyTag(statement, tag, value)
+				}
+
+				match := mt940TagRegex.FindStringSubmatch(line)
+				tag, value = match[1], match[2]
+
+				// a new :20: without block headers starts the next statement
+				if tag == "20" && (len(statement.entries) > 0 || statement.fields["statement_reference"] != "") {
+					pendingTag, pendingValue := tag, value
+					tag, value = "", ""
+					if !flush() {
+						return context.Cause(ctx)
+					}
+					tag, value = pendingTag, pendingValue
+				}
+
+			case tag != "":
+				// continuation of a multi line field
+				value += "\n" + line
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read mt940 file: %w", err)
+		}
+
+		if !flush() {
+			return context.Cause(ctx)
+		}
+		return nil
+	})
+
+	return recordsChan, nil
+}
+
+func (m *Mt940Ingester) applyTag(statement *mt940Statement, tag string, value string) *mt940Statement {
+	switch tag {
+	case "20":
+		statement.fields["statement_reference"] = strings.TrimSpace(value)
+
+	case "25":
+		statement.fields["account"] = strings.TrimSpace(value)
+
+	case "28C":
+		statement.fields["statement_number"] = strings.TrimSpace(value)
+
+	case "60F", "60M":
+		m.applyBalance(statement, "opening", value)
+
+	case "62F", "62M":
+		m.applyBalance(statement, "closing", value)
+
+	case "61":
+		lines := strings.SplitN(value, "\n", 2)
+		entry := map[string]string{"statement_line": strings.TrimSpace(value)}
+
+		if match := mt940StatementRegex.FindStringSubmatch(strings.TrimSpace(lines[0])); match != nil {
+			entry["value_date"] = match[1]
+			entry["entry_date"] = match[2]
+			entry["mark"] = match[3]
+			entry["amount"] = match[5]
+			entry["transaction_type"] = match[6]
+			entry["reference"] = strings.TrimSpace(match[7])
+			entry["bank_reference"] = strings.TrimSpace(match[8])
+		}
+
+		if len(lines) > 1 {
+			entry["supplementary"] = strings.TrimSpace(lines[1])
+		}
+
+		statement.entries = append(statement.entries, entry)
+
+	case "86":
+		// narrative belongs to the statement line right before it
+		if len(statement.entries) > 0 {
+			last := statement.entries[len(statement.entries)-1]
+			if _, ok := last["narrative"]; !ok {
+				last["narrative"] = strings.Join(strings.Fields(value), " ")
+			}
+		}
+	}
+
+	return statement
+}
+
+func (m *Mt940Ingester) applyBalance(statement *mt940Statement, prefix string, value string) {
+	match := mt940BalanceRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return
+	}
+
+	statement.fields[prefix+"_mark"] = match[1]
+	statement.fields[prefix+"_date"] = match[2]
+	statement.fields["currency"] = match[3]
+	statement.fields[prefix+"_balance"] = match[4]
+}
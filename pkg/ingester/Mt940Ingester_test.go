@@ -0,0 +1,97 @@
+package ingester
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMt940Ingester_Read(t *testing.T) {
+	ctx := context.Background()
+	dir := os.TempDir()
+	filePath := filepath.Join(dir, "test.mt940")
+	content := "{1:F01DBSSSGSGAXXX0000000000}{2:O940DBSSSGSGXXXXN}{4:\r\n" +
+		":20:STMT20250101\r\n" +
+		":25:1234567890\r\n" +
+		":28C:1/1\r\n" +
+		":60F:C250101IDR1000,00\r\n" +
+		":61:2501010101D4,00NTRFdbs_match_id_1//BANKREF1\r\n" +
+		"SUPPLEMENTARY\r\n" +
+		":86:REPAYMENT\r\n" +
+		"LOAN 1\r\n" +
+		":61:250101C10,NMSCNONREF//BANKREF2\r\n" +
+		":62F:C250101IDR1006,00\r\n" +
+		"-}\r\n" +
+		":20:STMT20250102\r\n" +
+		":25:1234567890\r\n" +
+		":60F:C250102IDR1006,00\r\n" +
+		":61:250102RC5,50NTRFREVERSAL\r\n" +
+		":62F:C250102IDR1000,50\r\n"
+
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create temp mt940 file: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	ingester := NewMt940Ingester()
+	recordsChan, err := ingester.Read(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	var records []map[string]string
+	for record := range recordsChan {
+		records = append(records, record)
+	}
+
+	expected := []map[string]string{
+		{
+			"statement_reference": "STMT20250101",
+			"account":             "1234567890",
+			"statement_number":    "1/1",
+			"opening_mark":        "C",
+			"opening_balance":     "1000,00",
+			"closing_balance":     "1006,00",
+			"currency":            "IDR",
+			"value_date":          "250101",
+			"entry_date":          "0101",
+			"mark":                "D",
+			"amount":              "4,00",
+			"transaction_type":    "NTRF",
+			"reference":           "dbs_match_id_1",
+			"bank_reference":      "BANKREF1",
+			"supplementary":       "SUPPLEMENTARY",
+			"narrative":           "REPAYMENT LOAN 1",
+		},
+		{
+			"statement_reference": "STMT20250101",
+			"mark":                "C",
+			"amount":              "10,",
+			"transaction_type":    "NMSC",
+			"reference":           "NONREF",
+			"bank_reference":      "BANKREF2",
+		},
+		{
+			"statement_reference": "STMT20250102",
+			"opening_balance":     "1006,00",
+			"closing_balance":     "1000,50",
+			"value_date":          "250102",
+			"mark":                "RC",
+			"amount":              "5,50",
+			"reference":           "REVERSAL",
+		},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+
+	for i, record := range records {
+		for k, v := range expected[i] {
+			if record[k] != v {
+				t.Errorf("expected record %d field %s to be %v, got %v", i, k, v, record[k])
+			}
+		}
+	}
+}
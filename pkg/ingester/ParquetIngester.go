@@ -0,0 +1,118 @@
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kevin-luvian/amartha-recon/pkg/pipeline"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Parquet reader, rows are read one at a time through the file row groups.
+// Values are converted to strings so the records can be handled by the same
+// parsers as csv records, null values become empty strings.
+type ParquetIngester struct {
+}
+
+func NewParquetIngester() *ParquetIngester {
+	return &ParquetIngester{}
+}
+
+func (p *ParquetIngester) Read(ctx context.Context, filepath string) (<-chan map[string]string, error) {
+	return readFiles(ctx, filepath, p.ReadStream)
+}
+
+func (p *ParquetIngester) ReadStream(ctx context.Context, reader io.ReadCloser) (<-chan map[string]string, error) {
+	recordsChan := make(chan map[string]string)
+
+	// parquet needs random access, decompressed streams are spooled to a temporary file first
+	file, closeFile, err := p.openRandomAccess(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		closeFile()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	parquetFile, err := parquet.OpenFile(file, stat.Size())
+	if err != nil {
+		closeFile()
+		return nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	pipeline.Go(ctx, func() (err error) {
+		defer close(recordsChan)
+		defer closeStream(closerFunc(closeFile), &err)
+
+		rowReader := parquet.NewReader(parquetFile)
+		defer rowReader.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+
+			default:
+				values := map[string]any{}
+				err := rowReader.Read(&values)
+				if err == io.EOF {
+					return nil
+				}
+
+				if err != nil {
+					return fmt.Errorf("failed to read parquet row: %w", err)
+				}
+
+				if !pipeline.Send(ctx, recordsChan, toRecord(values)) {
+					return context.Cause(ctx)
+				}
+			}
+		}
+	})
+
+	return recordsChan, nil
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error {
+	return c()
+}
+
+// openRandomAccess returns a file to read reader at offsets and the func closing it
+func (p *ParquetIngester) openRandomAccess(reader io.ReadCloser) (*os.File, func() error, error) {
+	switch file := reader.(type) {
+	case *os.File:
+		return file, file.Close, nil
+	case *checksumReader:
+		// offsets are read from the same handle, closing it checksums the whole file
+		return file.file, file.Close, nil
+	}
+
+	file, err := os.CreateTemp("", "recon-*.parquet")
+	if err != nil {
+		reader.Close()
+		return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	_, err = io.Copy(file, reader)
+	if closeErr := reader.Close(); err == nil {
+		// closing a checksumReader under the decompressor reports its checksum
+		err = closeErr
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, nil, fmt.Errorf("failed to spool parquet file: %w", err)
+	}
+
+	return file, func() error {
+		defer os.Remove(file.Name())
+		return file.Close()
+	}, nil
+}
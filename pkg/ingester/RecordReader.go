@@ -0,0 +1,22 @@
+package ingester
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// NewRecordReader picks a reader from the file extension, defaults to csv
+func NewRecordReader(path string) IRecordReader {
+	switch strings.ToLower(filepath.Ext(TrimCompressionExt(path))) {
+	case ".jsonl", ".ndjson":
+		return NewJsonlIngester()
+	case ".parquet":
+		return NewParquetIngester()
+	case ".xml":
+		return NewCamtIngester()
+	case ".mt940", ".mt942", ".sta":
+		return NewMt940Ingester()
+	default:
+		return NewCsvIngester()
+	}
+}
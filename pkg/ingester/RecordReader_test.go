@@ -0,0 +1,27 @@
+package ingester
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewRecordReader(t *testing.T) {
+	testCases := map[string]string{
+		"a.csv":      "*ingester.CsvIngester",
+		"a.JSONL":    "*ingester.JsonlIngester",
+		"a.ndjson":   "*ingester.JsonlIngester",
+		"a.parquet":  "*ingester.ParquetIngester",
+		"a.xml":      "*ingester.CamtIngester",
+		"a.mt940":    "*ingester.Mt940Ingester",
+		"a":          "*ingester.CsvIngester",
+		"a.csv.gz":   "*ingester.CsvIngester",
+		"a.jsonl.gz": "*ingester.JsonlIngester",
+	}
+
+	for path, expected := range testCases {
+		reader := fmt.Sprintf("%T", NewRecordReader(path))
+		if reader != expected {
+			t.Errorf("[%s] Expected %s, got %s", path, expected, reader)
+		}
+	}
+}
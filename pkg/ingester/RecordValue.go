@@ -0,0 +1,50 @@
+package ingester
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// formatRecordValue converts a decoded value into the string form parsers expect,
+// so typed sources stay compatible with csv based parsers
+func formatRecordValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case time.Time:
+		return v.Format(time.DateTime)
+	case map[string]any, []any:
+		// nested values are kept as json
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func toRecord(values map[string]any) map[string]string {
+	obj := make(map[string]string, len(values))
+	for key, value := range values {
+		obj[key] = formatRecordValue(value)
+	}
+	return obj
+}
@@ -1,8 +1,26 @@
 package ingester
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
-type ICsvIngester interface {
+// provenance keys added to every record read from a file
+const (
+	RecordFileKey = "_file"
+	RecordRowKey  = "_row"
+)
+
+type IRecordReader interface {
 	Read(ctx context.Context, filepath string) (<-chan map[string]string, error)
+}
+
+// IStreamReader reads records from an already opened file, the reader is closed once the stream ends
+type IStreamReader interface {
+	ReadStream(ctx context.Context, reader io.ReadCloser) (<-chan map[string]string, error)
+}
+
+type ICsvIngester interface {
+	IRecordReader
 	Write(ctx context.Context, filepath string, header []string, recordsChan <-chan map[string]string) error
 }
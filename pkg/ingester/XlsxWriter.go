@@ -0,0 +1,329 @@
+package ingester
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Streaming XLSX writer, an alternative to CsvIngester.Write for reviewers
+// working in Excel.
+//
+// Flow:
+// (1) AddSheet creates a sheet with a bold, frozen and filterable header row
+// (2) WriteRow appends the cells of a row to a temporary file per sheet, so a
+//     workbook never has to fit in memory
+// (3) Save assembles the package: workbook, styles and every spooled sheet
+//
+// Strings are written inline, no shared strings table is kept.
+
+type XlsxCellType int
+
+const (
+	XlsxText XlsxCellType = iota
+	XlsxNumber
+	XlsxAmount // number formatted #,##0.00
+)
+
+// cell styles, indexes into cellXfs of xlsxStyles
+const (
+	xlsxStyleDefault = 0
+	xlsxStyleHeader  = 1
+	xlsxStyleAmount  = 2
+)
+
+type XlsxColumn struct {
+	Header string
+	Type   XlsxCellType
+	Width  float64 // in characters, 0 keeps the Excel default
+}
+
+type XlsxWorkbook struct {
+	tempDir string
+	sheets  []*XlsxSheet
+}
+
+type XlsxSheet struct {
+	name    string
+	columns []XlsxColumn
+	file    *os.File
+	writer  *bufio.Writer
+	rows    int
+}
+
+// NewXlsxWorkbook spools sheets in tempDir, the default temporary directory when empty
+func NewXlsxWorkbook(tempDir string) *XlsxWorkbook {
+	return &XlsxWorkbook{tempDir: tempDir}
+}
+
+func (w *XlsxWorkbook) AddSheet(name string, columns []XlsxColumn) (*XlsxSheet, error) {
+	if name == "" || len(name) > 31 || strings.ContainsAny(name, `[]:*?/\`) {
+		return nil, fmt.Errorf("invalid sheet name %q", name)
+	}
+	for _, sheet := range w.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return nil, fmt.Errorf("duplicate sheet name %q", name)
+		}
+	}
+
+	file, err := os.CreateTemp(w.tempDir, "sheet-*.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &XlsxSheet{
+		name:    name,
+		columns: columns,
+		file:    file,
+		writer:  bufio.NewWriter(file),
+	}
+	w.sheets = append(w.sheets, sheet)
+
+	headers := make([]any, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	if err := sheet.writeRow(headers, true); err != nil {
+		return nil, err
+	}
+
+	return sheet, nil
+}
+
+// WriteRow writes one cell per column, values are string, int, int64 or
+// float64, nil leaves the cell empty
+func (s *XlsxSheet) WriteRow(values ...any) error {
+	return s.writeRow(values, false)
+}
+
+func (s *XlsxSheet) writeRow(values []any, isHeader bool) error {
+	rowNumber := s.rows + 1
+
+	var row bytes.Buffer
+	fmt.Fprintf(&row, `<row r="%d">`, rowNumber)
+
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		ref := xlsxColumnName(i) + strconv.Itoa(rowNumber)
+		style := xlsxStyleDefault
+		if isHeader {
+			style = xlsxStyleHeader
+		} else if i < len(s.columns) && s.columns[i].Type == XlsxAmount {
+			style = xlsxStyleAmount
+		}
+
+		switch v := value.(type) {
+		case string:
+			fmt.Fprintf(&row, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+			xml.EscapeText(&row, []byte(v))
+			row.WriteString(`</t></is></c>`)
+		case int:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		case int64:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		case float64:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return fmt.Errorf("unsupported cell value %T in sheet %s", value, s.name)
+		}
+	}
+
+	row.WriteString(`</row>`)
+	if _, err := s.writer.Write(row.Bytes()); err != nil {
+		return err
+	}
+
+	s.rows = rowNumber
+	return nil
+}
+
+// Save writes the workbook to filepath, the workbook can be saved once
+func (w *XlsxWorkbook) Save(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create workbook: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	if err := w.writePackage(archive); err != nil {
+		archive.Close()
+		return fmt.Errorf("failed to write workbook: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write workbook: %w", err)
+	}
+
+	return file.Close()
+}
+
+// Close removes the spooled sheets
+func (w *XlsxWorkbook) Close() error {
+	var firstErr error
+	for _, sheet := range w.sheets {
+		sheet.file.Close()
+		if err := os.Remove(sheet.file.Name()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	w.sheets = nil
+	return firstErr
+}
+
+func (w *XlsxWorkbook) writePackage(archive *zip.Writer) error {
+	var contentTypes, workbookSheets, workbookRels, definedNames strings.Builder
+
+	for i, sheet := range w.sheets {
+		id := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, id)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), id, id)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, id, id)
+		fmt.Fprintf(&definedNames, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!%s</definedName>`,
+			i, xmlEscape(strings.ReplaceAll(sheet.name, "'", "''")), sheet.filterRange(true))
+	}
+
+	stylesId := len(w.sheets) + 1
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesId)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xmlHeader +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			contentTypes.String() +
+			`</Types>`},
+		{"_rels/.rels", xmlHeader +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xmlHeader +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets.String() + `</sheets>` +
+			`<definedNames>` + definedNames.String() + `</definedNames>` +
+			`</workbook>`},
+		{"xl/_rels/workbook.xml.rels", xmlHeader +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			workbookRels.String() +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+
+	for _, part := range parts {
+		writer, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range w.sheets {
+		writer, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := sheet.writeTo(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *XlsxSheet) writeTo(writer io.Writer) error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+
+	var header strings.Builder
+	header.WriteString(xmlHeader)
+	header.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	header.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	hasWidths := false
+	for _, column := range s.columns {
+		hasWidths = hasWidths || column.Width > 0
+	}
+	if hasWidths {
+		header.WriteString(`<cols>`)
+		for i, column := range s.columns {
+			if column.Width > 0 {
+				fmt.Fprintf(&header, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(column.Width, 'f', -1, 64))
+			}
+		}
+		header.WriteString(`</cols>`)
+	}
+	header.WriteString(`<sheetData>`)
+
+	if _, err := io.WriteString(writer, header.String()); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, s.file); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(writer, `</sheetData><autoFilter ref="%s"/></worksheet>`, s.filterRange(false))
+	return err
+}
+
+// filterRange covers the header and every row, absolute for defined names
+func (s *XlsxSheet) filterRange(absolute bool) string {
+	lastColumn := xlsxColumnName(max(len(s.columns), 1) - 1)
+	if absolute {
+		return fmt.Sprintf("$A$1:$%s$%d", lastColumn, s.rows)
+	}
+	return fmt.Sprintf("A1:%s%d", lastColumn, s.rows)
+}
+
+// xlsxColumnName converts a 0-based index to A, B, ..., Z, AA, AB, ...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index += 1; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(value string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(value))
+	return buffer.String()
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// numFmtId 4 is the built in #,##0.00 format
+const xlsxStyles = xmlHeader +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
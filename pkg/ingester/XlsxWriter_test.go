@@ -0,0 +1,137 @@
+package ingester
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXlsxColumnName(t *testing.T) {
+	testCases := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+
+	for index, expected := range testCases {
+		if name := xlsxColumnName(index); name != expected {
+			t.Errorf("[%d] Expected %s, got %s", index, expected, name)
+		}
+	}
+}
+
+func readZipEntries(t *testing.T, path string) map[string]string {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	entries := map[string]string{}
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+
+		// every part has to be well formed xml
+		decoder := xml.NewDecoder(strings.NewReader(string(content)))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Fatalf("invalid xml in %s: %v", entry.Name, err)
+				}
+				break
+			}
+		}
+
+		entries[entry.Name] = string(content)
+	}
+
+	return entries
+}
+
+func TestXlsxWorkbook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.xlsx")
+
+	workbook := NewXlsxWorkbook(dir)
+	defer workbook.Close()
+
+	sheet, err := workbook.AddSheet("Matched", []XlsxColumn{
+		{Header: "id", Width: 20},
+		{Header: "amount", Type: XlsxAmount},
+		{Header: "row", Type: XlsxNumber},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sheet.WriteRow("txn <1> & co", 1234.5, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheet.WriteRow("txn_2", nil, int64(8)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := workbook.AddSheet("Errors", []XlsxColumn{{Header: "remark"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := workbook.AddSheet("errors", nil); err == nil {
+		t.Errorf("Expected duplicate sheet name error")
+	}
+	if _, err := workbook.AddSheet("a/b", nil); err == nil {
+		t.Errorf("Expected invalid sheet name error")
+	}
+
+	if err := sheet.WriteRow(struct{}{}); err == nil {
+		t.Errorf("Expected unsupported value error")
+	}
+
+	if err := workbook.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readZipEntries(t, path)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("Expected part %s", name)
+		}
+	}
+
+	if !strings.Contains(entries["xl/workbook.xml"], `<sheet name="Matched" sheetId="1" r:id="rId1"/>`) {
+		t.Errorf("Expected Matched sheet in workbook, got %s", entries["xl/workbook.xml"])
+	}
+
+	sheetXml := entries["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2" s="0" t="inlineStr"><is><t xml:space="preserve">txn &lt;1&gt; &amp; co</t></is></c>`,
+		`<c r="B2" s="2"><v>1234.5</v></c>`,
+		`<c r="C2" s="0"><v>7</v></c>`,
+		`<row r="3"><c r="A3" s="0" t="inlineStr"><is><t xml:space="preserve">txn_2</t></is></c><c r="C3" s="0"><v>8</v></c></row>`,
+		`<col min="1" max="1" width="20" customWidth="1"/>`,
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`,
+		`<autoFilter ref="A1:C3"/>`,
+	} {
+		if !strings.Contains(sheetXml, expected) {
+			t.Errorf("Expected %s in sheet, got %s", expected, sheetXml)
+		}
+	}
+
+	if err := workbook.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "sheet-*.xml"))
+	if len(files) != 0 {
+		t.Errorf("Expected spooled sheets removed, got %v", files)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected workbook kept, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Combinators built on the same rules as the stages in Pipeline.go, every
+// output channel is closed once the input is drained or ctx is done.
+
+// Tee broadcasts every item of inputChan to n outputs. Every output has to be
+// drained, the slowest consumer sets the pace of all of them.
+func Tee[T any](ctx context.Context, inputChan <-chan T, n int, opts ...StageOption) []<-chan T {
+	config := newStageConfig(10, opts)
+
+	outputChans := make([]chan T, n)
+	results := make([]<-chan T, n)
+	for i := range n {
+		outputChans[i] = make(chan T, config.bufferSize)
+		results[i] = outputChans[i]
+	}
+
+	Go(ctx, func() error {
+		defer func() {
+			for _, outputChan := range outputChans {
+				close(outputChan)
+			}
+		}()
+
+		for {
+			item, ok := Receive(ctx, inputChan, config.metrics)
+			if !ok {
+				return context.Cause(ctx)
+			}
+
+			for _, outputChan := range outputChans {
+				if !SendMeasured(ctx, outputChan, item, config.metrics) {
+					return context.Cause(ctx)
+				}
+			}
+		}
+	})
+
+	return results
+}
+
+// Batch groups items into slices of up to size items. With a positive timeout
+// a batch is also emitted once its first item waited that long, so a slow
+// input still makes progress. The last batch may be smaller. A size below 1
+// fails the stage, such a batch would never fill.
+func Batch[T any](ctx context.Context, inputChan <-chan T, size int, timeout time.Duration, opts ...StageOption) <-chan []T {
+	config := newStageConfig(10, opts)
+	outputChan := make(chan []T, config.bufferSize)
+
+	Go(ctx, func() error {
+		defer close(outputChan)
+
+		if size < 1 {
+			return fmt.Errorf("batch size must be at least 1, got %d", size)
+		}
+
+		batch := make([]T, 0, size)
+		var timer *time.Timer
+		var timerChan <-chan time.Time
+
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timerChan = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+
+			ok := SendMeasured(ctx, outputChan, batch, config.metrics)
+			batch = make([]T, 0, size)
+			return ok
+		}
+
+		for {
+			var start time.Time
+			if config.metrics != nil {
+				start = time.Now()
+			}
+
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+
+			case <-timerChan:
+				if !flush() {
+					return context.Cause(ctx)
+				}
+
+			case item, ok := <-inputChan:
+				config.metrics.observeReceive(start, ok)
+				if !ok {
+					if !flush() {
+						return context.Cause(ctx)
+					}
+					return nil
+				}
+
+				batch = append(batch, item)
+				if len(batch) == 1 && timeout > 0 {
+					timer = time.NewTimer(timeout)
+					timerChan = timer.C
+				}
+
+				if len(batch) >= size && !flush() {
+					return context.Cause(ctx)
+				}
+			}
+		}
+	})
+
+	return outputChan
+}
+
+// FlatMap emits every item returned by transformFunc, in order
+func FlatMap[In any, Out any](ctx context.Context, inputChan <-chan In, transformFunc func(In) []Out, opts ...StageOption) <-chan Out {
+	config := newStageConfig(10, opts)
+	outputChan := make(chan Out, config.bufferSize)
+
+	Go(ctx, func() error {
+		defer close(outputChan)
+
+		for {
+			item, ok := Receive(ctx, inputChan, config.metrics)
+			if !ok {
+				return context.Cause(ctx)
+			}
+
+			for _, transformedItem := range transformFunc(item) {
+				if !SendMeasured(ctx, outputChan, transformedItem, config.metrics) {
+					return context.Cause(ctx)
+				}
+			}
+		}
+	})
+
+	return outputChan
+}
+
+// Reduce folds every item of inputChan into initial, it blocks until inputChan
+// is drained and returns the cause of ctx when cancelled first
+func Reduce[In any, Acc any](ctx context.Context, inputChan <-chan In, initial Acc, reduceFunc func(Acc, In) Acc) (Acc, error) {
+	acc := initial
+
+	for {
+		item, ok := Receive(ctx, inputChan, nil)
+		if !ok {
+			return acc, context.Cause(ctx)
+		}
+
+		acc = reduceFunc(acc, item)
+	}
+}
+
+// MergeByKey merges inputs sorted by key into one sorted output. Items with
+// equal keys keep the order of their input in chans.
+func MergeByKey[T any, K cmp.Ordered](ctx context.Context, chans []<-chan T, key func(T) K, opts ...StageOption) <-chan T {
+	config := newStageConfig(10, opts)
+	outputChan := make(chan T, config.bufferSize)
+
+	Go(ctx, func() error {
+		defer close(outputChan)
+
+		// head item of every input still open
+		heads := make([]T, len(chans))
+		open := make([]bool, len(chans))
+
+		for i, c := range chans {
+			heads[i], open[i] = Receive(ctx, c, config.metrics)
+		}
+
+		for {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+
+			next := -1
+			for i := range chans {
+				if open[i] && (next == -1 || key(heads[i]) < key(heads[next])) {
+					next = i
+				}
+			}
+
+			if next == -1 {
+				return nil
+			}
+
+			if !SendMeasured(ctx, outputChan, heads[next], config.metrics) {
+				return context.Cause(ctx)
+			}
+
+			heads[next], open[next] = Receive(ctx, chans[next], config.metrics)
+		}
+	})
+
+	return outputChan
+}
@@ -0,0 +1,153 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sliceChan[T any](items ...T) <-chan T {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+func collect[T any](ch <-chan T) []T {
+	results := []T{}
+	for v := range ch {
+		results = append(results, v)
+	}
+	return results
+}
+
+func TestTee(t *testing.T) {
+	outputs := Tee(context.Background(), sliceChan(1, 2, 3), 2, WithBufferSize(0))
+
+	results := make([][]int, len(outputs))
+	var wg sync.WaitGroup
+	for i, output := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = collect(output)
+		}()
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if !reflect.DeepEqual(result, []int{1, 2, 3}) {
+			t.Errorf("Expected output %d to be [1 2 3], got %v", i, result)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	batches := collect(Batch(context.Background(), sliceChan(1, 2, 3, 4, 5), 2, 0))
+
+	expected := [][]int{{1, 2}, {3, 4}, {5}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("Expected %v, got %v", expected, batches)
+	}
+}
+
+func TestBatch_InvalidSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		group, ctx := WithGroup(context.Background())
+
+		if batches := collect(Batch(ctx, sliceChan(1, 2), size, 0)); len(batches) != 0 {
+			t.Errorf("[%d] Expected no batches, got %v", size, batches)
+		}
+		if err := group.Wait(); err == nil {
+			t.Errorf("[%d] Expected error for batch size", size)
+		}
+	}
+}
+
+func TestBatch_Timeout(t *testing.T) {
+	input := make(chan int)
+	output := Batch(context.Background(), input, 10, 10*time.Millisecond)
+
+	input <- 1
+	input <- 2
+
+	select {
+	case batch := <-output:
+		if !reflect.DeepEqual(batch, []int{1, 2}) {
+			t.Errorf("Expected [1 2], got %v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected partial batch after timeout")
+	}
+
+	input <- 3
+	close(input)
+
+	if batch := <-output; !reflect.DeepEqual(batch, []int{3}) {
+		t.Errorf("Expected [3], got %v", batch)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	output := FlatMap(context.Background(), sliceChan(1, 2, 3), func(x int) []int {
+		result := []int{}
+		for range x {
+			result = append(result, x)
+		}
+		return result
+	})
+
+	expected := []int{1, 2, 2, 3, 3, 3}
+	if results := collect(output); !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum, err := Reduce(context.Background(), sliceChan(1, 2, 3, 4), 0, func(acc int, x int) int {
+		return acc + x
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sum != 10 {
+		t.Errorf("Expected 10, got %d", sum)
+	}
+}
+
+func TestReduce_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stopErr := errors.New("stop")
+	cancel(stopErr)
+
+	_, err := Reduce(ctx, make(chan int), 0, func(acc int, x int) int {
+		return acc + x
+	})
+	if err != stopErr {
+		t.Errorf("Expected %v, got %v", stopErr, err)
+	}
+}
+
+func TestMergeByKey(t *testing.T) {
+	type item struct {
+		key   int
+		input string
+	}
+
+	a := sliceChan(item{1, "a"}, item{3, "a"}, item{5, "a"})
+	b := sliceChan(item{2, "b"}, item{3, "b"}, item{6, "b"})
+	c := sliceChan[item]()
+
+	output := MergeByKey(context.Background(), []<-chan item{a, b, c}, func(i item) int { return i.key })
+
+	expected := []item{{1, "a"}, {2, "b"}, {3, "a"}, {3, "b"}, {5, "a"}, {6, "b"}}
+	if results := collect(output); !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+}
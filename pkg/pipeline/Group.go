@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group tracks the goroutines of one pipeline run, errgroup style.
+//
+// The group travels inside the pipeline context, every stage started with Go
+// is waited on by Wait, and the first error returned by any stage cancels the
+// context so every other stage stops instead of blocking on a full channel.
+
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	mu      sync.Mutex
+	err     error
+}
+
+type groupKey struct{}
+
+func WithGroup(parent context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(parent)
+	group := &Group{cancel: cancel}
+	group.ctx = context.WithValue(ctx, groupKey{}, group)
+	return group, group.ctx
+}
+
+// GetGroup returns the group carried by ctx, nil when the context has none
+func GetGroup(ctx context.Context) *Group {
+	group, _ := ctx.Value(groupKey{}).(*Group)
+	return group
+}
+
+// Go runs fn on its own goroutine, a non nil error fails the whole group
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.Fail(err)
+		}
+	}()
+}
+
+// Fail records err as the group error if it is the first one and cancels the pipeline
+func (g *Group) Fail(err error) {
+	g.errOnce.Do(func() {
+		g.mu.Lock()
+		g.err = err
+		g.mu.Unlock()
+		g.cancel(err)
+	})
+}
+
+// Err returns the first error so far without waiting, cancellation of the parent context counts as an error
+func (g *Group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return g.err
+	}
+	return context.Cause(g.ctx)
+}
+
+// Wait blocks until every goroutine of the group returned and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	return g.Err()
+}
+
+// Go runs fn in the group of ctx, without a group the error is only printed
+func Go(ctx context.Context, fn func() error) {
+	if group := GetGroup(ctx); group != nil {
+		group.Go(fn)
+		return
+	}
+
+	go func() {
+		if err := fn(); err != nil && ctx.Err() == nil {
+			printError(err)
+		}
+	}()
+}
+
+// Fail fails the group of ctx, without a group the error is only printed
+func Fail(ctx context.Context, err error) {
+	if group := GetGroup(ctx); group != nil {
+		group.Fail(err)
+		return
+	}
+
+	printError(err)
+}
+
+// Send blocks until item is received or ctx is done, returns false when cancelled
+func Send[T any](ctx context.Context, ch chan<- T, item T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- item:
+		return true
+	}
+}
+
+func printError(err error) {
+	fmt.Printf("Pipeline error: %v\n", err)
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroup_FirstError(t *testing.T) {
+	group, ctx := WithGroup(context.Background())
+	firstErr := errors.New("first")
+
+	if GetGroup(ctx) != group {
+		t.Fatalf("Expected group in context")
+	}
+
+	Go(ctx, func() error {
+		return firstErr
+	})
+
+	Go(ctx, func() error {
+		<-ctx.Done()
+		return errors.New("second")
+	})
+
+	err := group.Wait()
+	if !errors.Is(err, firstErr) {
+		t.Fatalf("Expected %v, got %v", firstErr, err)
+	}
+
+	if !errors.Is(context.Cause(ctx), firstErr) {
+		t.Fatalf("Expected cause %v, got %v", firstErr, context.Cause(ctx))
+	}
+}
+
+func TestGroup_ParentCancelled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	group, ctx := WithGroup(parent)
+
+	Go(ctx, func() error {
+		<-ctx.Done()
+		return nil
+	})
+
+	if err := group.Err(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	cancel()
+	err := group.Wait()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestGroup_NoGroup(t *testing.T) {
+	ctx := context.Background()
+	if GetGroup(ctx) != nil {
+		t.Fatalf("Expected nil group")
+	}
+
+	done := make(chan struct{})
+	Go(ctx, func() error {
+		close(done)
+		return nil
+	})
+	<-done
+}
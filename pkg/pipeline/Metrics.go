@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage instrumentation, enabled per stage with WithMetrics.
+//
+// Every stage counts the items it received and sent and the time it spent
+// waiting on both ends. A stage mostly blocked on receive is starved by the
+// stage before it, a stage mostly blocked on send is held back by the stage
+// after it, so the slow stage of a run sits between the two. Times of stages
+// with several workers are summed over all workers.
+
+type StageMetrics struct {
+	Name           string
+	itemsIn        atomic.Int64
+	itemsOut       atomic.Int64
+	receiveBlocked atomic.Int64
+	sendBlocked    atomic.Int64
+}
+
+type StageStats struct {
+	Name           string
+	ItemsIn        int64
+	ItemsOut       int64
+	ReceiveBlocked time.Duration
+	SendBlocked    time.Duration
+}
+
+func (s StageStats) String() string {
+	return fmt.Sprintf(
+		"%s: in=%d out=%d blocked_on_receive=%s blocked_on_send=%s",
+		s.Name, s.ItemsIn, s.ItemsOut, s.ReceiveBlocked.Round(time.Millisecond), s.SendBlocked.Round(time.Millisecond),
+	)
+}
+
+func NewStageMetrics(name string) *StageMetrics {
+	return &StageMetrics{Name: name}
+}
+
+func (m *StageMetrics) Stats() StageStats {
+	return StageStats{
+		Name:           m.Name,
+		ItemsIn:        m.itemsIn.Load(),
+		ItemsOut:       m.itemsOut.Load(),
+		ReceiveBlocked: time.Duration(m.receiveBlocked.Load()),
+		SendBlocked:    time.Duration(m.sendBlocked.Load()),
+	}
+}
+
+// Metrics collects the stage metrics of one run in creation order
+type Metrics struct {
+	mu     sync.Mutex
+	stages []*StageMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Stage registers a new stage, a nil Metrics returns nil so instrumentation stays disabled
+func (m *Metrics) Stage(name string) *StageMetrics {
+	if m == nil {
+		return nil
+	}
+
+	stage := NewStageMetrics(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stages = append(m.stages, stage)
+
+	return stage
+}
+
+func (m *Metrics) Stats() []StageStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]StageStats, 0, len(m.stages))
+	for _, stage := range m.stages {
+		stats = append(stats, stage.Stats())
+	}
+	return stats
+}
+
+// Receive waits for the next item of ch, ok is false once ch is closed or ctx is done
+func Receive[T any](ctx context.Context, ch <-chan T, metrics *StageMetrics) (T, bool) {
+	var start time.Time
+	if metrics != nil {
+		start = time.Now()
+	}
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+
+	case item, ok := <-ch:
+		metrics.observeReceive(start, ok)
+		return item, ok
+	}
+}
+
+func (m *StageMetrics) observeReceive(start time.Time, ok bool) {
+	if m == nil {
+		return
+	}
+
+	m.receiveBlocked.Add(int64(time.Since(start)))
+	if ok {
+		m.itemsIn.Add(1)
+	}
+}
+
+// SendMeasured is Send recording the item and the time blocked on metrics
+func SendMeasured[T any](ctx context.Context, ch chan<- T, item T, metrics *StageMetrics) bool {
+	if metrics == nil {
+		return Send(ctx, ch, item)
+	}
+
+	start := time.Now()
+	ok := Send(ctx, ch, item)
+	metrics.sendBlocked.Add(int64(time.Since(start)))
+	if ok {
+		metrics.itemsOut.Add(1)
+	}
+
+	return ok
+}
+
+// StageOption configures a single pipeline stage
+type StageOption func(*stageConfig)
+
+type stageConfig struct {
+	bufferSize int
+	metrics    *StageMetrics
+}
+
+// WithBufferSize sets the buffer of the channel created by the stage, stages
+// writing to a channel owned by the caller ignore it
+func WithBufferSize(size int) StageOption {
+	return func(c *stageConfig) {
+		if size >= 0 {
+			c.bufferSize = size
+		}
+	}
+}
+
+// WithMetrics records the stage items and blocked times on metrics, nil disables it
+func WithMetrics(metrics *StageMetrics) StageOption {
+	return func(c *stageConfig) {
+		c.metrics = metrics
+	}
+}
+
+func newStageConfig(defaultBufferSize int, opts []StageOption) stageConfig {
+	config := stageConfig{bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMetrics_CountsItems(t *testing.T) {
+	metrics := NewMetrics()
+
+	input := make(chan int, 5)
+	for i := range 5 {
+		input <- i
+	}
+	close(input)
+
+	output := TransformChan(context.Background(), input, func(x int) (int, bool) {
+		return x, x%2 == 0
+	}, WithMetrics(metrics.Stage("filter")))
+
+	for range output {
+	}
+
+	stats := metrics.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 stage, got %d", len(stats))
+	}
+
+	if stats[0].Name != "filter" || stats[0].ItemsIn != 5 || stats[0].ItemsOut != 3 {
+		t.Errorf("Expected filter in=5 out=3, got %+v", stats[0])
+	}
+}
+
+func TestMetrics_BlockedTime(t *testing.T) {
+	metrics := NewMetrics()
+	stage := metrics.Stage("transform")
+
+	input := make(chan int)
+	output := TransformChan(context.Background(), input, func(x int) (int, bool) {
+		return x, true
+	}, WithBufferSize(0), WithMetrics(stage))
+
+	// slow producer, the stage waits on receive
+	go func() {
+		defer close(input)
+		time.Sleep(20 * time.Millisecond)
+		input <- 1
+		input <- 2
+	}()
+
+	<-output
+	// slow consumer, the stage waits on send
+	time.Sleep(20 * time.Millisecond)
+	for range output {
+	}
+
+	stats := stage.Stats()
+	if stats.ReceiveBlocked < 10*time.Millisecond {
+		t.Errorf("Expected receive blocked time, got %s", stats.ReceiveBlocked)
+	}
+	if stats.SendBlocked < 10*time.Millisecond {
+		t.Errorf("Expected send blocked time, got %s", stats.SendBlocked)
+	}
+}
+
+func TestMetrics_NilDisabled(t *testing.T) {
+	var metrics *Metrics
+
+	if stage := metrics.Stage("stage"); stage != nil {
+		t.Errorf("Expected nil stage, got %+v", stage)
+	}
+
+	input := make(chan int, 1)
+	input <- 1
+	close(input)
+
+	output := TransformChan(context.Background(), input, func(x int) (int, bool) {
+		return x, true
+	}, WithMetrics(metrics.Stage("stage")))
+
+	if v := <-output; v != 1 {
+		t.Errorf("Expected 1, got %d", v)
+	}
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// GetOrderedTransformerChans transforms with workerCount workers like
+// GetTransformerChans, but emits the outputs in input order.
+//
+// Flow:
+// (1) dispatcher tags every input with its index, at most bufferSize items
+//     can be dispatched and not yet emitted
+// (2) workers transform items in any order
+// (3) resequencer holds early results until every item before them is emitted,
+//     so the reordering buffer never grows beyond bufferSize
+
+type indexedItem[T any] struct {
+	index int
+	item  T
+}
+
+func GetOrderedTransformerChans[In any, Out any](
+	ctx context.Context,
+	inputChan <-chan In,
+	outputChan chan<- Out,
+	workerCount int,
+	bufferSize int,
+	transformFunc func(In) Out,
+	opts ...StageOption,
+) {
+	config := newStageConfig(0, opts)
+
+	if bufferSize < workerCount {
+		// smaller buffers would leave workers idle
+		bufferSize = workerCount
+	}
+
+	inFlight := make(chan struct{}, bufferSize)
+	jobChan := make(chan indexedItem[In])
+	resultChan := make(chan indexedItem[Out], workerCount)
+
+	Go(ctx, func() error {
+		defer close(jobChan)
+
+		index := 0
+		for {
+			item, ok := Receive(ctx, inputChan, config.metrics)
+			if !ok {
+				return context.Cause(ctx)
+			}
+
+			if !Send(ctx, inFlight, struct{}{}) {
+				return context.Cause(ctx)
+			}
+
+			if !Send(ctx, jobChan, indexedItem[In]{index: index, item: item}) {
+				return context.Cause(ctx)
+			}
+			index += 1
+		}
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+
+	for range workerCount {
+		Go(ctx, func() error {
+			defer wg.Done()
+			for job := range jobChan {
+				result := indexedItem[Out]{index: job.index, item: transformFunc(job.item)}
+				if !Send(ctx, resultChan, result) {
+					return context.Cause(ctx)
+				}
+			}
+			return nil
+		})
+	}
+
+	Go(ctx, func() error {
+		wg.Wait()
+		close(resultChan)
+		return nil
+	})
+
+	Go(ctx, func() error {
+		defer close(outputChan)
+
+		next := 0
+		pending := make(map[int]Out, bufferSize)
+
+		for result := range resultChan {
+			pending[result.index] = result.item
+
+			for {
+				item, ok := pending[next]
+				if !ok {
+					break
+				}
+
+				if !SendMeasured(ctx, outputChan, item, config.metrics) {
+					return context.Cause(ctx)
+				}
+
+				delete(pending, next)
+				next += 1
+				<-inFlight
+			}
+		}
+
+		return nil
+	})
+}
@@ -1,70 +1,108 @@
 package pipeline
 
 import (
+	"context"
 	"sync"
 )
 
+// Every stage stops when ctx is done, so an early returning consumer does not
+// leave upstream goroutines blocked on full channels. Stages started in a
+// context created by WithGroup are tracked by that group.
+//
+// Once the input is drained or ctx is done a stage returns context.Cause(ctx),
+// which is nil when the input was simply closed.
+
 func GetTransformerChans[In any, Out any](
+	ctx context.Context,
 	inputChan <-chan In,
 	outputChan chan<- Out,
 	workerCount int,
 	transformFunc func(In) Out,
+	opts ...StageOption,
 ) {
+	config := newStageConfig(0, opts)
+
 	var wg sync.WaitGroup
 	wg.Add(workerCount)
 
-	for i := range workerCount {
-		go func(id int) {
+	for range workerCount {
+		Go(ctx, func() error {
 			defer wg.Done()
-			for item := range inputChan {
-				transformedItem := transformFunc(item)
-				outputChan <- transformedItem
+			for {
+				item, ok := Receive(ctx, inputChan, config.metrics)
+				if !ok {
+					return context.Cause(ctx)
+				}
+
+				if !SendMeasured(ctx, outputChan, transformFunc(item), config.metrics) {
+					return context.Cause(ctx)
+				}
 			}
-		}(i)
+		})
 	}
 
-	go func() {
+	Go(ctx, func() error {
 		wg.Wait()
 		close(outputChan)
-	}()
+		return nil
+	})
 }
 
 func TransformChan[In any, Out any](
+	ctx context.Context,
 	inputChan <-chan In,
 	transformFunc func(In) (Out, bool),
+	opts ...StageOption,
 ) <-chan Out {
-	outputChan := make(chan Out, 10)
-	go func() {
+	config := newStageConfig(10, opts)
+	outputChan := make(chan Out, config.bufferSize)
+
+	Go(ctx, func() error {
 		defer close(outputChan)
-		for item := range inputChan {
-			if transformedItem, ok := transformFunc(item); ok {
-				outputChan <- transformedItem
+		for {
+			item, ok := Receive(ctx, inputChan, config.metrics)
+			if !ok {
+				return context.Cause(ctx)
+			}
+
+			transformedItem, ok := transformFunc(item)
+			if ok && !SendMeasured(ctx, outputChan, transformedItem, config.metrics) {
+				return context.Cause(ctx)
 			}
 		}
-	}()
+	})
 
 	return outputChan
 }
 
-func CombineChans[T any](chans ...<-chan T) <-chan T {
-	out := make(chan T)
+func CombineChans[T any](ctx context.Context, chans []<-chan T, opts ...StageOption) <-chan T {
+	config := newStageConfig(0, opts)
+	out := make(chan T, config.bufferSize)
 
 	var wg sync.WaitGroup
 	wg.Add(len(chans))
 
 	for _, c := range chans {
-		go func(c <-chan T) {
+		Go(ctx, func() error {
 			defer wg.Done()
-			for n := range c {
-				out <- n
+			for {
+				n, ok := Receive(ctx, c, config.metrics)
+				if !ok {
+					return context.Cause(ctx)
+				}
+
+				if !SendMeasured(ctx, out, n, config.metrics) {
+					return context.Cause(ctx)
+				}
 			}
-		}(c)
+		})
 	}
 
-	go func() {
+	Go(ctx, func() error {
 		wg.Wait()
 		close(out)
-	}()
+		return nil
+	})
 
 	return out
 }
@@ -1,7 +1,10 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestGetTransformerChans(t *testing.T) {
@@ -15,7 +18,7 @@ func TestGetTransformerChans(t *testing.T) {
 	close(input)
 
 	transform := func(x int) int { return x * 2 }
-	GetTransformerChans(input, output, 2, transform)
+	GetTransformerChans(context.Background(), input, output, 2, transform)
 
 	results := []int{}
 
@@ -48,7 +51,7 @@ func TestTransformChan(t *testing.T) {
 		return 0, false
 	}
 
-	output := TransformChan(input, transform)
+	output := TransformChan(context.Background(), input, transform)
 	results := []int{}
 
 	for v := range output {
@@ -76,7 +79,7 @@ func TestCombineChans(t *testing.T) {
 	close(ch1)
 	close(ch2)
 
-	combined := CombineChans(ch1, ch2)
+	combined := CombineChans(context.Background(), []<-chan int{ch1, ch2})
 	results := []int{}
 
 	for v := range combined {
@@ -99,3 +102,112 @@ func TestCombineChans(t *testing.T) {
 		}
 	}
 }
+
+func TestPipeline_Cancel(t *testing.T) {
+	group, ctx := WithGroup(context.Background())
+	cancelErr := errors.New("consumer stopped")
+
+	// endless producer, nothing downstream closes on its own
+	input := make(chan int)
+	Go(ctx, func() error {
+		defer close(input)
+		for i := 0; ; i++ {
+			if !Send(ctx, input, i) {
+				return context.Cause(ctx)
+			}
+		}
+	})
+
+	transformed := make(chan int)
+	GetTransformerChans(ctx, input, transformed, 4, func(x int) int { return x * 2 })
+	filtered := TransformChan(ctx, transformed, func(x int) (int, bool) { return x, true })
+	combined := CombineChans(ctx, []<-chan int{filtered})
+
+	// consumer returns early after reading a few items
+	for range 3 {
+		<-combined
+	}
+	group.Fail(cancelErr)
+
+	done := make(chan error)
+	go func() { done <- group.Wait() }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, cancelErr) {
+			t.Fatalf("Expected %v, got %v", cancelErr, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected every stage to stop after cancel, still blocked")
+	}
+}
+
+func TestGetOrderedTransformerChans(t *testing.T) {
+	input := make(chan int)
+	output := make(chan int)
+
+	go func() {
+		defer close(input)
+		for i := range 100 {
+			input <- i
+		}
+	}()
+
+	// later items finish first, output must still follow input order
+	transform := func(x int) int {
+		time.Sleep(time.Duration(100-x) * 10 * time.Microsecond)
+		return x * 2
+	}
+	GetOrderedTransformerChans(context.Background(), input, output, 4, 8, transform)
+
+	results := []int{}
+	for v := range output {
+		results = append(results, v)
+	}
+
+	if len(results) != 100 {
+		t.Fatalf("Expected 100 results, got %d", len(results))
+	}
+
+	for i, v := range results {
+		if v != i*2 {
+			t.Fatalf("Expected %d at index %d, got %d", i*2, i, v)
+		}
+	}
+}
+
+func TestGetOrderedTransformerChans_BoundedBuffer(t *testing.T) {
+	input := make(chan int, 10)
+	output := make(chan int)
+	for i := range 10 {
+		input <- i
+	}
+	close(input)
+
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	transform := func(x int) int {
+		started <- x
+		if x == 0 {
+			// first item blocks, everything after it has to wait in the buffer
+			<-release
+		}
+		return x
+	}
+	GetOrderedTransformerChans(context.Background(), input, output, 2, 4, transform)
+
+	time.Sleep(50 * time.Millisecond)
+	if len(started) != 4 {
+		t.Fatalf("Expected 4 items in flight, got %d", len(started))
+	}
+
+	close(release)
+	results := []int{}
+	for v := range output {
+		results = append(results, v)
+	}
+
+	if len(results) != 10 {
+		t.Fatalf("Expected 10 results, got %d", len(results))
+	}
+}
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ITable backed by a bbolt file, tables survive process restarts.
+//
+// Every table is a pair of buckets in the store:
+//   <name>/items  key -> encoded object
+//   <name>/paths  search keys joined by \x00 -> key
+//
+// Path lookups are prefix scans over <name>/paths, which keeps the sorted
+// layout of the search tree on disk.
+//
+// Every table of a store shares one write transaction, committed once it holds
+// commitSize writes or when the store is closed, so a row costs no fsync of
+// its own. Reads go through the same transaction and see the pending writes.
+
+const (
+	pathSeparator = "\x00"
+
+	// objects read per transaction by Each, fn runs outside the transaction so it can write
+	defaultEachBatchSize = 1000
+
+	// writes per committed transaction
+	defaultCommitSize = 10000
+)
+
+// ICodec converts the objects of a table to bytes and back
+type ICodec interface {
+	Encode(obj IHashable) ([]byte, error)
+	Decode(data []byte) (IHashable, error)
+}
+
+// GobCodec encodes objects of type T with encoding/gob
+type GobCodec[T IHashable] struct{}
+
+func (GobCodec[T]) Encode(obj IHashable) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(obj.(T)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (IHashable, error) {
+	var obj T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// BoltStore is safe for concurrent use, its tables are not
+type BoltStore struct {
+	db         *bolt.DB
+	mu         sync.Mutex
+	tx         *bolt.Tx // pending write transaction, nil until the next write
+	writes     int
+	commitSize int
+}
+
+// OpenBoltStore opens or creates the store file at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db, commitSize: defaultCommitSize}, nil
+}
+
+// Close commits the pending writes and closes the file
+func (s *BoltStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (s *BoltStore) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	tx := s.tx
+	s.tx, s.writes = nil, 0
+	return tx.Commit()
+}
+
+// update runs fn in the pending write transaction, a failed fn rolls back every pending write
+func (s *BoltStore) update(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx == nil {
+		tx, err := s.db.Begin(true)
+		if err != nil {
+			return err
+		}
+		s.tx = tx
+	}
+
+	if err := fn(s.tx); err != nil {
+		s.tx.Rollback()
+		s.tx, s.writes = nil, 0
+		return err
+	}
+
+	s.writes += 1
+	if s.writes >= s.commitSize {
+		return s.commit()
+	}
+	return nil
+}
+
+// view runs fn in the pending write transaction, or in a read transaction when nothing is pending
+func (s *BoltStore) view(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.View(fn)
+}
+
+// Clear drops every table of the store, for a store reused as scratch space of a new run
+func (s *BoltStore) Clear() error {
+	return s.update(func(tx *bolt.Tx) error {
+		names := [][]byte{}
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, bytes.Clone(name))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Table opens the table called name, creating it when missing
+func (s *BoltStore) Table(name string, codec ICodec) (*BoltTable, error) {
+	table := &BoltTable{
+		store:       s,
+		codec:       codec,
+		batchSize:   defaultEachBatchSize,
+		itemsBucket: []byte(name + "/items"),
+		pathsBucket: []byte(name + "/paths"),
+	}
+
+	err := s.update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(table.itemsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(table.pathsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+type BoltTable struct {
+	store       *BoltStore
+	codec       ICodec
+	batchSize   int
+	itemsBucket []byte
+	pathsBucket []byte
+	keep        func(key []byte) bool // keys visited by Each, nil visits every key
+	err         error
+}
+
+// Partition returns a view of the table whose Each only visits the keys kept by keep,
+// so several owners can share one table by partitioning its keys. Lookups see every key.
+func (b *BoltTable) Partition(keep func(key []byte) bool) *BoltTable {
+	return &BoltTable{
+		store:       b.store,
+		codec:       b.codec,
+		batchSize:   b.batchSize,
+		itemsBucket: b.itemsBucket,
+		pathsBucket: b.pathsBucket,
+		keep:        keep,
+	}
+}
+
+// fail keeps the first error, like HashTable a BoltTable is not safe for concurrent use
+func (b *BoltTable) fail(err error) {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *BoltTable) Err() error {
+	return b.err
+}
+
+func (b *BoltTable) Put(obj IHashable) {
+	key, searchKeys := obj.Hash()
+
+	data, err := b.codec.Encode(obj)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+
+	b.fail(b.store.update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(b.itemsBucket).Put([]byte(key), data); err != nil {
+			return err
+		}
+		return tx.Bucket(b.pathsBucket).Put([]byte(joinPath(searchKeys)), []byte(key))
+	}))
+}
+
+func (b *BoltTable) Remove(obj IHashable) {
+	key, searchKeys := obj.Hash()
+
+	b.fail(b.store.update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(b.itemsBucket).Delete([]byte(key)); err != nil {
+			return err
+		}
+		return tx.Bucket(b.pathsBucket).Delete([]byte(joinPath(searchKeys)))
+	}))
+}
+
+func (b *BoltTable) GetById(key string) IHashable {
+	var obj IHashable
+
+	b.fail(b.store.view(func(tx *bolt.Tx) error {
+		var err error
+		obj, err = b.get(tx, []byte(key))
+		return err
+	}))
+
+	return obj
+}
+
+func (b *BoltTable) get(tx *bolt.Tx, key []byte) (IHashable, error) {
+	data := tx.Bucket(b.itemsBucket).Get(key)
+	if data == nil {
+		return nil, nil
+	}
+	return b.codec.Decode(data)
+}
+
+func (b *BoltTable) GetFirstMatchByPath(path []string) IHashable {
+	var obj IHashable
+
+	b.fail(b.store.view(func(tx *bolt.Tx) error {
+		prefix := []byte(joinPath(path) + pathSeparator)
+		cursor := tx.Bucket(b.pathsBucket).Cursor()
+
+		pathKey, key := cursor.Seek(prefix)
+		if pathKey == nil || !bytes.HasPrefix(pathKey, prefix) {
+			return nil
+		}
+
+		var err error
+		obj, err = b.get(tx, key)
+		return err
+	}))
+
+	return obj
+}
+
+func (b *BoltTable) IsPathContainsOneValue(path []string) (string, bool) {
+	var value string
+	var isOne bool
+
+	b.fail(b.store.view(func(tx *bolt.Tx) error {
+		prefix := []byte(joinPath(path) + pathSeparator)
+		cursor := tx.Bucket(b.pathsBucket).Cursor()
+
+		pathKey, key := cursor.Seek(prefix)
+		if pathKey == nil || !bytes.HasPrefix(pathKey, prefix) {
+			return nil
+		}
+		value = string(key)
+
+		pathKey, _ = cursor.Next()
+		isOne = pathKey == nil || !bytes.HasPrefix(pathKey, prefix)
+		return nil
+	}))
+
+	if !isOne {
+		return "", false
+	}
+	return value, true
+}
+
+// Each reads batchSize keys per transaction and calls fn outside of it
+func (b *BoltTable) Each(fn func(obj IHashable) bool) {
+	var after []byte
+
+	for {
+		batch := make([]IHashable, 0, b.batchSize)
+		scanned := 0
+
+		err := b.store.view(func(tx *bolt.Tx) error {
+			cursor := tx.Bucket(b.itemsBucket).Cursor()
+
+			key, data := cursor.First()
+			if after != nil {
+				key, data = cursor.Seek(after)
+				if key != nil && bytes.Equal(key, after) {
+					key, data = cursor.Next()
+				}
+			}
+
+			for ; key != nil && scanned < b.batchSize; key, data = cursor.Next() {
+				scanned += 1
+				after = append(after[:0], key...)
+				if b.keep != nil && !b.keep(key) {
+					continue
+				}
+
+				obj, err := b.codec.Decode(data)
+				if err != nil {
+					return err
+				}
+				batch = append(batch, obj)
+			}
+
+			return nil
+		})
+		if err != nil {
+			b.fail(err)
+			return
+		}
+
+		for _, obj := range batch {
+			if !fn(obj) {
+				return
+			}
+		}
+
+		if scanned < b.batchSize {
+			return
+		}
+	}
+}
+
+func joinPath(path []string) string {
+	return strings.Join(path, pathSeparator)
+}
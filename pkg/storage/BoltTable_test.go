@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func BoltTable_Open(t *testing.T, path string) (*BoltStore, *BoltTable) {
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := store.Table("test", GobCodec[*TestHashableObj]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, table
+}
+
+func TestBoltTable_ITable(t *testing.T) {
+	store, table := BoltTable_Open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	tables := map[string]ITable{
+		"hash": NewHashTable(),
+		"bolt": table,
+	}
+
+	for name, table := range tables {
+		table.Put(&TestHashableObj{Id: "txn_1", Date: "2025-01-01", Source: "test"})
+		table.Put(&TestHashableObj{Id: "txn_2", Date: "2025-01-01", Source: "test"})
+		table.Put(&TestHashableObj{Id: "txn_3", Date: "2025-01-02", Source: "test"})
+
+		obj := table.GetById("test|2025-01-01|txn_1")
+		if obj == nil || obj.(*TestHashableObj).Id != "txn_1" {
+			t.Errorf("[%s] Expected txn_1, got %v", name, obj)
+		}
+
+		if obj := table.GetById("test|2025-01-01|txn_9"); obj != nil {
+			t.Errorf("[%s] Expected nil, got %v", name, obj)
+		}
+
+		obj = table.GetFirstMatchByPath([]string{"test", "2025-01-02"})
+		if obj == nil || obj.(*TestHashableObj).Id != "txn_3" {
+			t.Errorf("[%s] Expected txn_3, got %v", name, obj)
+		}
+
+		if obj := table.GetFirstMatchByPath([]string{"test", "2025-01-03"}); obj != nil {
+			t.Errorf("[%s] Expected nil, got %v", name, obj)
+		}
+
+		if _, ok := table.IsPathContainsOneValue([]string{"test", "2025-01-01"}); ok {
+			t.Errorf("[%s] Expected two values on 2025-01-01", name)
+		}
+
+		key, ok := table.IsPathContainsOneValue([]string{"test", "2025-01-02"})
+		if !ok || key != "test|2025-01-02|txn_3" {
+			t.Errorf("[%s] Expected one value on 2025-01-02, got %s %t", name, key, ok)
+		}
+
+		table.Remove(&TestHashableObj{Id: "txn_2", Date: "2025-01-01", Source: "test"})
+		key, ok = table.IsPathContainsOneValue([]string{"test", "2025-01-01"})
+		if !ok || key != "test|2025-01-01|txn_1" {
+			t.Errorf("[%s] Expected one value on 2025-01-01 after remove, got %s %t", name, key, ok)
+		}
+
+		if err := table.Err(); err != nil {
+			t.Errorf("[%s] Unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestBoltTable_EachRemove(t *testing.T) {
+	store, table := BoltTable_Open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	table.batchSize = 2
+	for _, id := range []string{"txn_1", "txn_2", "txn_3", "txn_4", "txn_5"} {
+		table.Put(&TestHashableObj{Id: id, Date: "2025-01-01", Source: "test"})
+	}
+
+	seen := map[string]bool{}
+	table.Each(func(obj IHashable) bool {
+		seen[obj.(*TestHashableObj).Id] = true
+		table.Remove(obj)
+		return true
+	})
+
+	if len(seen) != 5 {
+		t.Errorf("Expected 5 objects, got %v", seen)
+	}
+
+	count := 0
+	table.Each(func(obj IHashable) bool {
+		count += 1
+		return true
+	})
+
+	if count != 0 || table.Err() != nil {
+		t.Errorf("Expected empty table, got %d objects, err %v", count, table.Err())
+	}
+}
+
+func TestBoltTable_Persistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	store, table := BoltTable_Open(t, path)
+	table.Put(&TestHashableObj{Id: "txn_1", Date: "2025-01-01", Source: "test"})
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, table = BoltTable_Open(t, path)
+	defer store.Close()
+
+	obj := table.GetById("test|2025-01-01|txn_1")
+	if obj == nil || obj.(*TestHashableObj).Date != "2025-01-01" {
+		t.Errorf("Expected txn_1 after reopening, got %v", obj)
+	}
+}
+
+func TestBoltStore_CommitInChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	store, table := BoltTable_Open(t, path)
+	// the pending table creation is committed with txn_1
+	store.commitSize = 2
+	for _, id := range []string{"txn_1", "txn_2"} {
+		table.Put(&TestHashableObj{Id: id, Date: "2025-01-01", Source: "test"})
+	}
+
+	// txn_2 is still pending, reads see it
+	if store.tx == nil || store.writes != 1 {
+		t.Errorf("Expected one pending write, got %d", store.writes)
+	}
+	if obj := table.GetById("test|2025-01-01|txn_2"); obj == nil {
+		t.Errorf("Expected the pending txn_2, got nil")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, table = BoltTable_Open(t, path)
+	defer store.Close()
+
+	if obj := table.GetById("test|2025-01-01|txn_2"); obj == nil {
+		t.Errorf("Expected txn_2 committed on close, got nil")
+	}
+}
+
+func TestBoltStore_Clear(t *testing.T) {
+	store, table := BoltTable_Open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	table.Put(&TestHashableObj{Id: "txn_1", Date: "2025-01-01", Source: "test"})
+	if err := store.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := store.Table("test", GobCodec[*TestHashableObj]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj := table.GetById("test|2025-01-01|txn_1"); obj != nil {
+		t.Errorf("Expected an empty table, got %v", obj)
+	}
+}
+
+func TestBoltTable_Partition(t *testing.T) {
+	store, table := BoltTable_Open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer store.Close()
+
+	table.batchSize = 2
+	for _, date := range []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-04"} {
+		table.Put(&TestHashableObj{Id: "txn", Date: date, Source: "test"})
+	}
+
+	partition := table.Partition(func(key []byte) bool {
+		return bytes.HasSuffix(key, []byte("2025-01-04|txn"))
+	})
+
+	seen := []string{}
+	partition.Each(func(obj IHashable) bool {
+		seen = append(seen, obj.(*TestHashableObj).Date)
+		return true
+	})
+	if !reflect.DeepEqual(seen, []string{"2025-01-04"}) {
+		t.Errorf("Expected only 2025-01-04, got %v", seen)
+	}
+
+	if obj := partition.GetById("test|2025-01-01|txn"); obj == nil {
+		t.Errorf("Expected lookups to see every key")
+	}
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"slices"
+)
+
+// Sorts more items than fit in memory.
+//
+// Flow:
+// (1) Add buffers items, every runSize items the buffer is sorted and spilled
+//     to a gob encoded run file in dir
+// (2) Sorted k-way merges the run files and the last in memory buffer, holding
+//     one item per run
+// (3) Close removes the run files
+//
+// Items are encoded with encoding/gob, so only exported fields survive a spill.
+// Equal items keep their insertion order.
+
+type ExternalSorter[T any] struct {
+	dir     string
+	runSize int
+	compare func(a, b T) int
+	buffer  []T
+	runs    []string
+}
+
+func NewExternalSorter[T any](dir string, runSize int, compare func(a, b T) int) *ExternalSorter[T] {
+	if runSize <= 0 {
+		runSize = 1
+	}
+
+	return &ExternalSorter[T]{
+		dir:     dir,
+		runSize: runSize,
+		compare: compare,
+		buffer:  make([]T, 0, runSize),
+	}
+}
+
+func (s *ExternalSorter[T]) Add(item T) error {
+	s.buffer = append(s.buffer, item)
+	if len(s.buffer) < s.runSize {
+		return nil
+	}
+
+	return s.spill()
+}
+
+// RunCount returns how many runs were spilled to disk so far
+func (s *ExternalSorter[T]) RunCount() int {
+	return len(s.runs)
+}
+
+func (s *ExternalSorter[T]) spill() error {
+	slices.SortStableFunc(s.buffer, s.compare)
+
+	file, err := os.CreateTemp(s.dir, "run-*.gob")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, file.Name())
+
+	writer := bufio.NewWriter(file)
+	encoder := gob.NewEncoder(writer)
+	for _, item := range s.buffer {
+		if err := encoder.Encode(item); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	s.buffer = s.buffer[:0]
+	return file.Close()
+}
+
+// Sorted returns an iterator over every added item in order, no item can be added afterwards
+func (s *ExternalSorter[T]) Sorted() (*SortedIterator[T], error) {
+	slices.SortStableFunc(s.buffer, s.compare)
+
+	iterator := &SortedIterator[T]{compare: s.compare}
+
+	// runs first, so equal items of earlier runs come first
+	for _, run := range s.runs {
+		file, err := os.Open(run)
+		if err != nil {
+			iterator.Close()
+			return nil, err
+		}
+
+		cursor := &runCursor[T]{
+			order:   len(iterator.cursors),
+			file:    file,
+			decoder: gob.NewDecoder(bufio.NewReader(file)),
+		}
+		iterator.cursors = append(iterator.cursors, cursor)
+	}
+
+	iterator.cursors = append(iterator.cursors, &runCursor[T]{
+		order:  len(iterator.cursors),
+		buffer: s.buffer,
+	})
+
+	for _, cursor := range iterator.cursors {
+		ok, err := cursor.advance()
+		if err != nil {
+			iterator.Close()
+			return nil, err
+		}
+		if ok {
+			iterator.active = append(iterator.active, cursor)
+		}
+	}
+
+	heap.Init(iterator)
+	return iterator, nil
+}
+
+// Close removes the spilled runs
+func (s *ExternalSorter[T]) Close() error {
+	var errs []error
+	for _, run := range s.runs {
+		if err := os.Remove(run); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	s.runs = nil
+	s.buffer = nil
+	return errors.Join(errs...)
+}
+
+type runCursor[T any] struct {
+	order   int
+	head    T
+	file    *os.File
+	decoder *gob.Decoder
+	buffer  []T
+}
+
+// advance loads the next head, ok is false once the run is exhausted
+func (c *runCursor[T]) advance() (bool, error) {
+	if c.decoder == nil {
+		if len(c.buffer) == 0 {
+			return false, nil
+		}
+		c.head = c.buffer[0]
+		c.buffer = c.buffer[1:]
+		return true, nil
+	}
+
+	var item T
+	if err := c.decoder.Decode(&item); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	c.head = item
+	return true, nil
+}
+
+// SortedIterator yields the items of an ExternalSorter in order
+type SortedIterator[T any] struct {
+	compare func(a, b T) int
+	cursors []*runCursor[T]
+	active  []*runCursor[T]
+	err     error
+}
+
+// Next returns the next item, ok is false once every item was returned or on error
+func (it *SortedIterator[T]) Next() (T, bool) {
+	var zero T
+	if it.err != nil || len(it.active) == 0 {
+		return zero, false
+	}
+
+	cursor := heap.Pop(it).(*runCursor[T])
+	item := cursor.head
+
+	ok, err := cursor.advance()
+	if err != nil {
+		it.err = err
+		return zero, false
+	}
+	if ok {
+		heap.Push(it, cursor)
+	}
+
+	return item, true
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *SortedIterator[T]) Err() error {
+	return it.err
+}
+
+func (it *SortedIterator[T]) Close() error {
+	var errs []error
+	for _, cursor := range it.cursors {
+		if cursor.file != nil {
+			errs = append(errs, cursor.file.Close())
+		}
+	}
+
+	it.cursors = nil
+	it.active = nil
+	return errors.Join(errs...)
+}
+
+// heap.Interface over the active cursors
+
+func (it *SortedIterator[T]) Len() int {
+	return len(it.active)
+}
+
+func (it *SortedIterator[T]) Less(i, j int) bool {
+	if result := it.compare(it.active[i].head, it.active[j].head); result != 0 {
+		return result < 0
+	}
+	return it.active[i].order < it.active[j].order
+}
+
+func (it *SortedIterator[T]) Swap(i, j int) {
+	it.active[i], it.active[j] = it.active[j], it.active[i]
+}
+
+func (it *SortedIterator[T]) Push(x any) {
+	it.active = append(it.active, x.(*runCursor[T]))
+}
+
+func (it *SortedIterator[T]) Pop() any {
+	last := it.active[len(it.active)-1]
+	it.active = it.active[:len(it.active)-1]
+	return last
+}
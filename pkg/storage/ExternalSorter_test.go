@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"cmp"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+type TestSortableObj struct {
+	Key   int
+	Order int
+}
+
+func compareTestSortableObj(a, b TestSortableObj) int {
+	return cmp.Compare(a.Key, b.Key)
+}
+
+func TestExternalSorter_Sorted(t *testing.T) {
+	dir := t.TempDir()
+	sorter := NewExternalSorter(dir, 7, compareTestSortableObj)
+	defer sorter.Close()
+
+	random := rand.New(rand.NewSource(1))
+	for i := range 100 {
+		if err := sorter.Add(TestSortableObj{Key: random.Intn(10), Order: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sorter.RunCount() != 14 {
+		t.Errorf("Expected 14 runs, got %d", sorter.RunCount())
+	}
+
+	iterator, err := sorter.Sorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+
+	count := 0
+	var previous TestSortableObj
+	for item, ok := iterator.Next(); ok; item, ok = iterator.Next() {
+		if count > 0 {
+			if item.Key < previous.Key {
+				t.Fatalf("Expected sorted keys, got %d after %d", item.Key, previous.Key)
+			}
+			if item.Key == previous.Key && item.Order < previous.Order {
+				t.Fatalf("Expected insertion order for equal keys, got %d after %d", item.Order, previous.Order)
+			}
+		}
+		previous = item
+		count += 1
+	}
+
+	if iterator.Err() != nil {
+		t.Fatal(iterator.Err())
+	}
+
+	if count != 100 {
+		t.Errorf("Expected 100 items, got %d", count)
+	}
+}
+
+func TestExternalSorter_InMemory(t *testing.T) {
+	sorter := NewExternalSorter(t.TempDir(), 10, compareTestSortableObj)
+	defer sorter.Close()
+
+	for _, key := range []int{3, 1, 2} {
+		sorter.Add(TestSortableObj{Key: key})
+	}
+
+	if sorter.RunCount() != 0 {
+		t.Errorf("Expected no spilled runs, got %d", sorter.RunCount())
+	}
+
+	iterator, err := sorter.Sorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []int{}
+	for item, ok := iterator.Next(); ok; item, ok = iterator.Next() {
+		keys = append(keys, item.Key)
+	}
+
+	if len(keys) != 3 || keys[0] != 1 || keys[1] != 2 || keys[2] != 3 {
+		t.Errorf("Expected [1 2 3], got %v", keys)
+	}
+}
+
+func TestExternalSorter_CloseRemovesRuns(t *testing.T) {
+	dir := t.TempDir()
+	sorter := NewExternalSorter(dir, 1, compareTestSortableObj)
+
+	sorter.Add(TestSortableObj{Key: 1})
+	sorter.Add(TestSortableObj{Key: 2})
+
+	if err := sorter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected run files removed, got %d files", len(entries))
+	}
+}
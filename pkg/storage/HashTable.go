@@ -78,6 +78,20 @@ func (h *HashTable) Remove(obj IHashable) {
 	h.SearchTree.Delete(searchKeys)
 }
 
+func (h *HashTable) Each(fn func(obj IHashable) bool) {
+	// removing entries while ranging a map is safe
+	for _, v := range h.Table {
+		if !fn(v) {
+			return
+		}
+	}
+}
+
+// Err is always nil, an in memory table cannot fail
+func (h *HashTable) Err() error {
+	return nil
+}
+
 func (h *HashTable) GetValues() []IHashable {
 	all := make([]IHashable, 0, len(h.Table))
 	for _, v := range h.Table {
@@ -3,3 +3,19 @@ package storage
 type IHashable interface {
 	Hash() (key string, searchKeys []string)
 }
+
+// ITable stores hashables by key and by search path, see HashTable.
+//
+// Disk backed tables can fail, a failed operation behaves as if the table was
+// empty and the first error is kept for Err, check it once a batch of
+// operations is done.
+type ITable interface {
+	Put(obj IHashable)
+	GetById(key string) IHashable
+	GetFirstMatchByPath(path []string) IHashable
+	IsPathContainsOneValue(path []string) (string, bool)
+	Remove(obj IHashable)
+	// Each calls fn for every stored object until fn returns false, fn may remove objects
+	Each(fn func(obj IHashable) bool)
+	Err() error
+}